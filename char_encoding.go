@@ -4,52 +4,108 @@ import (
 	"strings"
 )
 
-var (
-	stepOneEncoding = map[string]string{
-		"^": "^5E",
+const hexDigits = "0123456789ABCDEF"
+
+// nonASCIITable escapes only bytes outside ASCII
+var nonASCIITable = func() *[256]bool {
+	table := new([256]bool)
+	for c := 0x80; c < 0x100; c++ {
+		table[c] = true
 	}
-	stepTwoEncoding = map[string]string{
-		" ":  "^20",
-		"\"": "^22",
-		"*":  "^2A",
-		"/":  "^2F",
-		":":  "^3A",
-		"<":  "^3C",
-		">":  "^3E",
-		"?":  "^3F",
-		"\\": "^5C",
-		"|":  "^7C",
+	return table
+}()
+
+// newEscapeTable returns a table of the bytes escaped as ^XX in
+// filenames. "^" is always escaped so decoding is unambiguous and
+// control characters (including NUL) aren't safe in filenames. chars
+// are the other characters to escape, see EncodingProfile.
+func newEscapeTable(chars string) *[256]bool {
+	table := new([256]bool)
+	for c := 0; c < 0x20; c++ {
+		table[c] = true
 	}
-)
+	table[0x7F] = true
+	table['^'] = true
+	for i := 0; i < len(chars); i++ {
+		table[chars[i]] = true
+	}
+	return table
+}
 
-func charEncode(s string) string {
-	//NOTE: we need to replace ^ with ^5e and avoid collisions with other hex values
-	// we split the string into an array of substrings then replace each one as as need to.
-	p := strings.Split(s, "")
-	for i, target := range p {
-		if val, ok := stepOneEncoding[target]; ok == true {
-			p[i] = val
+// encodeWith escapes the bytes of s marked in table as ^XX (two upper
+// case hex digits). Multibyte UTF-8 characters are left as is.
+func encodeWith(table *[256]bool, s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if table[c] {
+			sb.WriteByte('^')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0x0F])
+		} else {
+			sb.WriteByte(c)
 		}
 	}
-	s = strings.Join(p, "")
-	for target, replacement := range stepTwoEncoding {
-		if strings.Contains(s, target) {
-			s = strings.Replace(s, target, replacement, -1)
-		}
+	return sb.String()
+}
+
+// unhex returns the value of a hex digit, either case, or -1
+func unhex(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
 	}
-	return s
+	return -1
 }
 
+// charEncode escapes s using the current Encoding profile
+func charEncode(s string) string {
+	return Encoding.EncodeValue(s)
+}
+
+// charDecode replaces every ^XX sequence (X a hex digit, either case)
+// with the byte it encodes in a single pass. Sequences of escaped
+// bytes decode to multibyte UTF-8 characters. A "^" not followed by
+// two hex digits is left as is.
 func charDecode(s string) string {
-	for replacement, target := range stepTwoEncoding {
-		if strings.Contains(s, target) {
-			s = strings.Replace(s, target, replacement, -1)
-		}
+	i := strings.IndexByte(s, '^')
+	if i < 0 {
+		return s
 	}
-	for replacement, target := range stepOneEncoding {
-		if strings.Contains(s, target) {
-			s = strings.Replace(s, target, replacement, -1)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	sb.WriteString(s[0:i])
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '^' && i+2 < len(s) {
+			hi, lo := unhex(s[i+1]), unhex(s[i+2])
+			if hi >= 0 && lo >= 0 {
+				sb.WriteByte(byte(hi<<4 | lo))
+				i += 2
+				continue
+			}
 		}
+		sb.WriteByte(c)
 	}
-	return s
+	return sb.String()
+}
+
+// EncodeValue returns a value with the characters that aren't safe in
+// a namaste filename escaped as ^XX using the current Encoding
+// profile, e.g. "Twain, Mark" becomes "Twain,^20Mark".
+// DecodeValue(EncodeValue(s)) always returns s.
+func EncodeValue(s string) string {
+	return charEncode(s)
+}
+
+// DecodeValue returns a value with every ^XX escape replaced by the
+// byte it encodes, so escapes from other implementations are decoded
+// even if EncodeValue wouldn't produce them.
+func DecodeValue(s string) string {
+	return charDecode(s)
 }
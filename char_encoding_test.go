@@ -46,3 +46,59 @@ func TestCharEncoding(t *testing.T) {
 		}
 	}
 }
+
+func TestValueCodec(t *testing.T) {
+	expected := map[string]string{
+		"Twain, Mark":        "Twain,^20Mark",
+		"2^10":               "2^5E10",
+		"line 1\nline 2":     "line^201^0Aline^202",
+		"nul\x00tab\t":       "nul^00tab^09",
+		"del\x7f":            "del^7F",
+		"Gödel, Escher/Bach": "Gödel,^20Escher^2FBach",
+		"日本語":                "日本語",
+		"^5E":                "^5E5E",
+		"":                   "",
+	}
+	for s, encoded := range expected {
+		if result := EncodeValue(s); result != encoded {
+			t.Errorf("EncodeValue(%q) expected %q, got %q", s, encoded, result)
+		}
+		if result := DecodeValue(encoded); result != s {
+			t.Errorf("DecodeValue(%q) expected %q, got %q", encoded, s, result)
+		}
+	}
+
+	// Escapes from other implementations
+	decoded := map[string]string{
+		"G^c3^b6del":         "Gödel",
+		"^E6^97^A5^E6^9C^AC": "日本",
+		"a^2eb":              "a.b",
+		"^":                  "^",
+		"^2":                 "^2",
+		"^zz":                "^zz",
+		"100^":               "100^",
+		"^^5E":               "^^",
+		"..^2E":              "...",
+	}
+	for s, expect := range decoded {
+		if result := DecodeValue(s); result != expect {
+			t.Errorf("DecodeValue(%q) expected %q, got %q", s, expect, result)
+		}
+	}
+
+	// Every byte round trips and the result is the same on every run
+	var all []byte
+	for c := 0; c < 256; c++ {
+		all = append(all, byte(c))
+	}
+	s := string(all) + "^^5E^^"
+	encoded := EncodeValue(s)
+	for i := 0; i < 10; i++ {
+		if result := EncodeValue(s); result != encoded {
+			t.Errorf("EncodeValue() is not deterministic, %q != %q", result, encoded)
+		}
+	}
+	if result := DecodeValue(encoded); result != s {
+		t.Errorf("DecodeValue(EncodeValue(s)) expected %q, got %q", s, result)
+	}
+}
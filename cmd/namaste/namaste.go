@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"path"
 	"strings"
 
@@ -80,8 +82,118 @@ THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
 	dName    string
 	asValues bool
 	asJSON   bool
+
+	// Find options
+	maxDepth       int
+	followSymlinks bool
+	ignorePatterns string
+
+	// Scan options
+	workers int
+	ordered bool
+
+	// Type profiles config file
+	profilesFName string
+
+	// Filename encoding profile, transliteration and Unicode normalization
+	encodingName      string
+	transliterate     bool
+	normalizationForm string
+
+	// Validate options
+	reportFormat string
+
+	// Repair options
+	repairFrom string
+	dryRun     bool
+
+	// Write options
+	setModeName string
+	removeAll   bool
 )
 
+// setField sets a field using the -mode option and reports the files
+// created and removed
+func setField(out io.Writer, eout io.Writer, key string, field string, value string) int {
+	mode, err := namaste.ParseSetMode(setModeName)
+	if err != nil {
+		fmt.Fprintf(eout, "%s\n", err)
+		return 1
+	}
+	result, err := namaste.SetValue(dName, field, value, mode)
+	if err != nil {
+		fmt.Fprintf(eout, "%s\n", err)
+		return 1
+	}
+	if asJSON {
+		m := map[string]interface{}{
+			key:       result.Filename,
+			"created": result.Created,
+			"removed": result.Removed,
+		}
+		src, err := json.Marshal(m)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "%s\n", src)
+	}
+	if verbose {
+		for _, name := range result.Created {
+			fmt.Fprintf(out, "created %s\n", name)
+		}
+		for _, name := range result.Removed {
+			fmt.Fprintf(out, "removed %s\n", name)
+		}
+		if result.Filename == "" {
+			fmt.Fprintf(out, "%s is already set\n", key)
+		}
+	}
+	return 0
+}
+
+// displayTags writes the tags as JSON or as a list of filenames
+func displayTags(out io.Writer, eout io.Writer, tags []namaste.Tag) int {
+	if asJSON {
+		src, err := json.Marshal(tags)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "%s\n", src)
+		return 0
+	}
+	l := []string{}
+	for _, tag := range tags {
+		l = append(l, tag.Raw)
+	}
+	fmt.Fprintf(out, "namastes: %s\n", strings.Join(l, ", "))
+	return 0
+}
+
+// displayValues writes the values read from tag files as JSON or
+// one per line, warning about any that disagree with their filename
+func displayValues(out io.Writer, eout io.Writer, values []*namaste.Value) int {
+	l := []string{}
+	for _, val := range values {
+		if val.Mismatch {
+			fmt.Fprintf(eout, "WARNING: %q contents %q disagree with filename\n", val.Name, val.Value)
+		}
+		l = append(l, val.Value)
+	}
+	if asJSON {
+		src, err := json.Marshal(l)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		fmt.Fprintf(out, "%s\n", src)
+		return 0
+	}
+	fmt.Fprintf(out, "%s\n", strings.Join(l, "\n"))
+	return 0
+}
+
 func main() {
 	appName := path.Base(os.Args[0])
 	app := cli.NewCli(namaste.Version)
@@ -109,9 +221,13 @@ func main() {
 	app.BoolVar(&generateManPage, "generate-manpage", false, "output documentation in 'nroff -man' format")
 
 	// App Options
-	app.StringVar(&dName, "d,directory", ".", "directory")
+	app.StringVar(&dName, "d,directory", ".", "directory, local path, s3://BUCKET/PREFIX or gs://BUCKET/PREFIX")
 	app.BoolVar(&asJSON, "json", false, "output in JSON format")
 	app.BoolVar(&asValues, "values", false, "output value only, one per line")
+	app.StringVar(&encodingName, "encoding", "", "filename encoding, namaste, posix, windows, s3, web or ascii (default $NAMASTE_ENCODING or namaste)")
+	app.BoolVar(&transliterate, "transliterate", false, "write ASCII only filenames, the contents keep the exact value (default $NAMASTE_TRANSLITERATE)")
+	app.StringVar(&normalizationForm, "normalize", "", "Unicode normalization of values, NFC, NFD, NFKC, NFKD or none (default $NAMASTE_NORMALIZE or NFC)")
+	app.StringVar(&profilesFName, "profiles", "", "JSON file of type profiles to add to the registry (default $NAMASTE_PROFILES)")
 
 	// Read Verbs
 	verb := app.NewVerb("get", "returns namaste metadata of a directory if known", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
@@ -122,31 +238,20 @@ func main() {
 		}
 		args = flagSet.Args()
 
-		l, err := namaste.Get(dName, args)
-		if err != nil {
-			fmt.Fprintf(eout, "%s\n", err)
-			return 1
-		}
 		if asValues {
-			for i, val := range l {
-				l[i] = namaste.Decode(val)
-			}
-		}
-		if asJSON {
-			src, err := json.Marshal(l)
+			values, err := namaste.GetValues(dName, args)
 			if err != nil {
 				fmt.Fprintf(eout, "%s\n", err)
 				return 1
 			}
-			fmt.Fprintf(out, "%s\n", src)
-			return 0
+			return displayValues(out, eout, values)
 		}
-		if asValues {
-			fmt.Fprintf(out, "%s\n", strings.Join(l, "\n"))
-		} else {
-			fmt.Fprintf(out, "namastes: %s\n", strings.Join(l, ", "))
+		tags, err := namaste.Get(dName, args)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
 		}
-		return 0
+		return displayTags(out, eout, tags)
 	})
 	verb.SetParams("[TYPE]", "[TYPE ...]")
 	verb.BoolVar(&asValues, "values", false, "output values only, one per line")
@@ -160,13 +265,13 @@ func main() {
 		}
 		args = flagSet.Args()
 
-		m, err := namaste.GetTypes(dName)
+		types, err := namaste.GetDirTypes(dName)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
 		if asJSON {
-			src, err := json.Marshal(m)
+			src, err := json.Marshal(types)
 			if err != nil {
 				fmt.Fprintf(eout, "%s\n", err)
 				return 1
@@ -174,22 +279,12 @@ func main() {
 			fmt.Fprintf(out, "%s\n", src)
 			return 0
 		}
-		for _, val := range m {
-			name, major, minor := "", "", ""
-			if s, ok := val["name"]; ok == true {
-				name = s
-			}
-			if s, ok := val["major"]; ok == true {
-				major = s
-			}
-			if s, ok := val["minor"]; ok == true {
-				minor = s
-			}
+		for _, t := range types {
 			// Insert newline if required
 			if asValues {
-				fmt.Fprintf(out, "%s_%s.%s\n", name, major, minor)
+				fmt.Fprintf(out, "%s\n", t)
 			} else {
-				fmt.Fprintf(out, "namaste - directory type %s - version %s %s\n", name, major, minor)
+				fmt.Fprintf(out, "namaste - directory type %s - version %s %s\n", t.Name, t.Major, t.Minor)
 			}
 		}
 		return 0
@@ -197,171 +292,505 @@ func main() {
 	verb.BoolVar(&asValues, "values", false, "output values only, one per line")
 	verb.BoolVar(&asJSON, "j,json", false, "set json output")
 
-	// Write Verbs
-	verb = app.NewVerb("type", "set the type of a directory", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+	verb = app.NewVerb("is", "checks a directory's type, exit code 0 if it matches, 1 if not and 2 on error", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
 		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
-			return 1
+			return 2
 		}
 		args = flagSet.Args()
 		if len(args) == 0 {
-			fmt.Fprintf(eout, "Missing value\n")
-			return 1
+			fmt.Fprintf(eout, "Missing type constraint\n")
+			return 2
 		}
 
-		s, err := namaste.DirType(dName, args[0])
+		// Every constraint must match
+		exitCode := 0
+		for _, constraint := range args {
+			types, err := namaste.MatchTypes(dName, constraint)
+			if err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 2
+			}
+			if len(types) == 0 {
+				exitCode = 1
+			}
+			if verbose {
+				for _, t := range types {
+					fmt.Fprintf(out, "%s\n", t)
+				}
+			}
+		}
+		return exitCode
+	})
+	verb.SetParams("TYPE-CONSTRAINT", "[TYPE-CONSTRAINT ...]")
+	verb.BoolVar(&verbose, "V,verbose", false, "list the matching types")
+
+	verb = app.NewVerb("profiles", "lists the registered type profiles", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
-		if asJSON && s != "" {
-			m := map[string]string{
-				"type": s,
-			}
-			src, err := json.Marshal(m)
+		args = flagSet.Args()
+
+		profiles := namaste.TypeProfiles()
+		if asJSON {
+			src, err := json.MarshalIndent(profiles, "", "    ")
 			if err != nil {
 				fmt.Fprintf(eout, "%s\n", err)
 				return 1
 			}
 			fmt.Fprintf(out, "%s\n", src)
+			return 0
 		}
-		if verbose && s != "" {
-			fmt.Fprintf(out, "%s\n", s)
+		for _, profile := range profiles {
+			l := []string{}
+			if len(profile.Fields) > 0 {
+				l = append(l, "fields: "+strings.Join(profile.Fields, ", "))
+			}
+			if len(profile.Files) > 0 {
+				l = append(l, "files: "+strings.Join(profile.Files, ", "))
+			}
+			fmt.Fprintf(out, "%s%s - %s\n", profile.Name, profile.Versions, profile.Description)
+			if len(l) > 0 {
+				fmt.Fprintf(out, "    %s\n", strings.Join(l, "; "))
+			}
 		}
 		return 0
 	})
 	verb.BoolVar(&asJSON, "j,json", false, "set json output")
-	verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
 
-	verb = app.NewVerb("who", "sets the who value of a directory", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+	verb = app.NewVerb("getx", "returns the extension (x_) namaste of a directory if known", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
 		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
 		args = flagSet.Args()
-		if len(args) == 0 {
-			fmt.Fprintf(eout, "Missing value\n")
+
+		if asValues {
+			kinds := []string{namaste.ExtensionPrefix}
+			if len(args) > 0 {
+				kinds = []string{}
+				for _, arg := range args {
+					s, err := namaste.ExtensionName(arg)
+					if err != nil {
+						fmt.Fprintf(eout, "%s\n", err)
+						return 1
+					}
+					kinds = append(kinds, s)
+				}
+			}
+			values, err := namaste.GetValues(dName, kinds)
+			if err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 1
+			}
+			return displayValues(out, eout, values)
+		}
+		tags, err := namaste.GetExtensions(dName, args)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
+		return displayTags(out, eout, tags)
+	})
+	verb.SetParams("[NAME]", "[NAME ...]")
+	verb.BoolVar(&asValues, "values", false, "output values only, one per line")
+	verb.BoolVar(&asJSON, "j,json", false, "set json output")
 
-		s, err := namaste.Who(dName, args[0])
+	verb = app.NewVerb("find", "finds the directories with namaste in a tree", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
-		if asJSON && s != "" {
-			m := map[string]string{
-				"who": s,
+		args = flagSet.Args()
+		root := dName
+		if len(args) > 0 {
+			root = args[0]
+		}
+		opts := &namaste.WalkOptions{
+			MaxDepth:       maxDepth,
+			FollowSymlinks: followSymlinks,
+		}
+		if ignorePatterns != "" {
+			opts.Ignore = strings.Split(ignorePatterns, ",")
+		}
+		if asJSON {
+			found, err := namaste.Find(root, opts)
+			if err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 1
 			}
-			src, err := json.Marshal(m)
+			src, err := json.Marshal(found)
 			if err != nil {
 				fmt.Fprintf(eout, "%s\n", err)
 				return 1
 			}
 			fmt.Fprintf(out, "%s\n", src)
+			return 0
 		}
-		if verbose && s != "" {
-			fmt.Fprintf(out, "%s\n", s)
+		// Report directories as they are found
+		err = namaste.Walk(root, opts, func(dName string, tags []namaste.Tag) error {
+			if asValues {
+				fmt.Fprintf(out, "%s\n", dName)
+				return nil
+			}
+			l := []string{}
+			for _, tag := range tags {
+				l = append(l, tag.Raw)
+			}
+			fmt.Fprintf(out, "%s: %s\n", dName, strings.Join(l, ", "))
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
 		}
 		return 0
 	})
+	verb.SetParams("[PATH]")
+	verb.IntVar(&maxDepth, "depth", 0, "maximum depth to descend, 0 for no limit")
+	verb.BoolVar(&followSymlinks, "follow", false, "follow symbolic links to directories")
+	verb.StringVar(&ignorePatterns, "ignore", "", "comma separated patterns of directories to skip, e.g. .git,tmp")
+	verb.BoolVar(&asValues, "values", false, "output directory paths only, one per line")
 	verb.BoolVar(&asJSON, "j,json", false, "set json output")
-	verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
 
-	verb = app.NewVerb("what", "sets the what value of a directory", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+	verb = app.NewVerb("scan", "reports the namaste of every directory in a tree, reading many directories at once", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
 		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
 		args = flagSet.Args()
+		root := dName
+		if len(args) > 0 {
+			root = args[0]
+		}
+		opts := &namaste.ScanOptions{
+			WalkOptions: namaste.WalkOptions{
+				MaxDepth:       maxDepth,
+				FollowSymlinks: followSymlinks,
+			},
+			Workers: workers,
+			Ordered: ordered,
+		}
+		if ignorePatterns != "" {
+			opts.Ignore = strings.Split(ignorePatterns, ",")
+		}
+		// Stop scanning on interrupt
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		results, err := namaste.Scan(ctx, root, opts)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		exitCode := 0
+		for result := range results {
+			if result.Err != nil {
+				fmt.Fprintf(eout, "%s: %s\n", result.Path, result.Err)
+				exitCode = 1
+				continue
+			}
+			// One JSON object per line so reports can be streamed
+			if asJSON {
+				src, err := json.Marshal(result)
+				if err != nil {
+					fmt.Fprintf(eout, "%s\n", err)
+					return 1
+				}
+				fmt.Fprintf(out, "%s\n", src)
+				continue
+			}
+			l := []string{}
+			for _, tag := range result.Tags {
+				l = append(l, tag.Raw)
+			}
+			fmt.Fprintf(out, "%s: %s\n", result.Path, strings.Join(l, ", "))
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintf(eout, "scan interrupted\n")
+			return 1
+		}
+		return exitCode
+	})
+	verb.SetParams("[PATH]")
+	verb.IntVar(&workers, "workers", namaste.DefaultWorkers, "number of directories to read at once")
+	verb.BoolVar(&ordered, "ordered", false, "report directories in the same order as find")
+	verb.IntVar(&maxDepth, "depth", 0, "maximum depth to descend, 0 for no limit")
+	verb.BoolVar(&followSymlinks, "follow", false, "follow symbolic links to directories")
+	verb.StringVar(&ignorePatterns, "ignore", "", "comma separated patterns of directories to skip, e.g. .git,tmp")
+	verb.BoolVar(&asJSON, "j,json", false, "output one JSON object per line")
+
+	verb = app.NewVerb("validate", "checks directories against the Namaste Spec, exit code 0 if ok, 1 for warnings and 2 for errors", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+		err := flagSet.Parse(args)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 2
+		}
+		args = flagSet.Args()
 		if len(args) == 0 {
-			fmt.Fprintf(eout, "Missing value\n")
+			args = []string{dName}
+		}
+		if asJSON {
+			reportFormat = "json"
+		}
+
+		reports := []*namaste.Report{}
+		worst := namaste.SeverityInfo
+		for _, p := range args {
+			report, err := namaste.Validate(p)
+			if err != nil {
+				report = &namaste.Report{
+					Path: p,
+					Findings: []*namaste.Finding{
+						&namaste.Finding{
+							Severity: namaste.SeverityError,
+							Code:     "unreadable",
+							Message:  err.Error(),
+						},
+					},
+				}
+			}
+			if report.Worst() > worst {
+				worst = report.Worst()
+			}
+			reports = append(reports, report)
+		}
+		switch strings.ToLower(reportFormat) {
+		case "json":
+			src, err := json.MarshalIndent(reports, "", "    ")
+			if err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 2
+			}
+			fmt.Fprintf(out, "%s\n", src)
+		case "junit":
+			src, err := namaste.JUnitReport(reports)
+			if err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 2
+			}
+			fmt.Fprintf(out, "%s\n", src)
+		case "text", "":
+			for _, report := range reports {
+				for _, finding := range report.Findings {
+					fmt.Fprintf(out, "%s: %s\n", report.Path, finding)
+				}
+				if verbose && len(report.Findings) == 0 {
+					fmt.Fprintf(out, "%s: ok\n", report.Path)
+				}
+			}
+		default:
+			fmt.Fprintf(eout, "unknown format %q, use text, json or junit\n", reportFormat)
+			return 2
+		}
+		return int(worst)
+	})
+	verb.SetParams("[PATH]", "[PATH ...]")
+	verb.StringVar(&reportFormat, "format", "text", "report format, text, json or junit")
+	verb.BoolVar(&asJSON, "j,json", false, "same as -format json")
+	verb.BoolVar(&verbose, "V,verbose", false, "report directories without findings")
+
+	verb = app.NewVerb("repair", "fixes namaste whose filenames and contents disagree", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+		err := flagSet.Parse(args)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		args = flagSet.Args()
+		target := dName
+		if len(args) > 0 {
+			target = args[0]
+		}
+		source, err := namaste.ParseRepairSource(repairFrom)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
 
-		s, err := namaste.What(dName, args[0])
+		plan, err := namaste.PlanRepair(target, source)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
-		if asJSON && s != "" {
-			m := map[string]string{
-				"what": s,
+		if dryRun == false {
+			if err := plan.Apply(); err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 1
 			}
-			src, err := json.Marshal(m)
+		}
+		if asJSON {
+			src, err := json.MarshalIndent(plan, "", "    ")
 			if err != nil {
 				fmt.Fprintf(eout, "%s\n", err)
 				return 1
 			}
 			fmt.Fprintf(out, "%s\n", src)
+			return 0
 		}
-		if verbose && s != "" {
-			fmt.Fprintf(out, "%s\n", s)
-		}
+		fmt.Fprintf(out, "%s", plan.Diff())
 		return 0
 	})
+	verb.SetParams("[PATH]")
+	verb.StringVar(&repairFrom, "from", "contents", "rebuild from \"contents\" (filenames are fixed) or \"filenames\" (contents are fixed)")
+	verb.BoolVar(&dryRun, "n,dry-run", false, "show the changes without making them")
 	verb.BoolVar(&asJSON, "j,json", false, "set json output")
-	verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
 
-	verb = app.NewVerb("when", "sets the when value of a directory", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+	// Write Verbs
+	fieldVerbs := []struct {
+		field       string
+		description string
+	}{
+		{"type", "set the type of a directory"},
+		{"who", "sets the who value of a directory"},
+		{"what", "sets the what value of a directory"},
+		{"when", "sets the when value of a directory"},
+		{"where", "sets the where value of a directory"},
+		{"note", "sets a note for a directory"},
+	}
+	for _, fieldVerb := range fieldVerbs {
+		field := fieldVerb.field
+		verb = app.NewVerb(field, fieldVerb.description, func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+			err := flagSet.Parse(args)
+			if err != nil {
+				fmt.Fprintf(eout, "%s\n", err)
+				return 1
+			}
+			args = flagSet.Args()
+			if len(args) == 0 {
+				fmt.Fprintf(eout, "Missing value\n")
+				return 1
+			}
+
+			return setField(out, eout, field, field, args[0])
+		})
+		verb.BoolVar(&asJSON, "j,json", false, "set json output")
+		verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
+		verb.StringVar(&setModeName, "mode", "add", "add another value, replace existing values or set-if-absent")
+	}
+
+	verb = app.NewVerb("x", "sets an extension (x_) value of a directory", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
 		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
 		args = flagSet.Args()
-		if len(args) == 0 {
-			fmt.Fprintf(eout, "Missing value\n")
+		if len(args) < 2 {
+			fmt.Fprintf(eout, "Missing name and value\n")
 			return 1
 		}
 
-		s, err := namaste.When(dName, args[0])
+		name, err := namaste.ExtensionName(args[0])
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		return setField(out, eout, name, name, args[1])
+	})
+	verb.SetParams("NAME", "VALUE")
+	verb.BoolVar(&asJSON, "j,json", false, "set json output")
+	verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
+	verb.StringVar(&setModeName, "mode", "add", "add another value, replace existing values or set-if-absent")
+
+	verb = app.NewVerb("set", "sets several fields of a directory at once", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
-		if asJSON && s != "" {
-			m := map[string]string{
-				"when": s,
+		args = flagSet.Args()
+		if len(args) == 0 || len(args)%2 != 0 {
+			fmt.Fprintf(eout, "Expected FIELD VALUE pairs\n")
+			return 1
+		}
+		mode, err := namaste.ParseSetMode(setModeName)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+			return 1
+		}
+		fields := map[string]string{}
+		for i := 0; i < len(args); i += 2 {
+			if _, ok := fields[args[i]]; ok {
+				fmt.Fprintf(eout, "%q is given more than once\n", args[i])
+				return 1
 			}
-			src, err := json.Marshal(m)
+			fields[args[i]] = args[i+1]
+		}
+
+		results, err := namaste.SetFields(dName, fields, mode)
+		if err != nil {
+			fmt.Fprintf(eout, "%s\n", err)
+		}
+		if asJSON {
+			src, err := json.Marshal(results)
 			if err != nil {
 				fmt.Fprintf(eout, "%s\n", err)
 				return 1
 			}
 			fmt.Fprintf(out, "%s\n", src)
 		}
-		if verbose && s != "" {
-			fmt.Fprintf(out, "%s\n", s)
+		if verbose {
+			for _, result := range results {
+				for _, name := range result.Created {
+					fmt.Fprintf(out, "created %s\n", name)
+				}
+				for _, name := range result.Removed {
+					fmt.Fprintf(out, "removed %s\n", name)
+				}
+			}
+		}
+		if err != nil {
+			return 1
 		}
 		return 0
 	})
+	verb.SetParams("FIELD", "VALUE", "[FIELD VALUE ...]")
 	verb.BoolVar(&asJSON, "j,json", false, "set json output")
 	verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
+	verb.StringVar(&setModeName, "mode", "add", "add another value, replace existing values or set-if-absent")
 
-	verb = app.NewVerb("where", "sets the where value of a directory", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
+	verb = app.NewVerb("rm", "removes the tags of a field holding a value", func(in io.Reader, out io.Writer, eout io.Writer, args []string, flagSet *flag.FlagSet) int {
 		err := flagSet.Parse(args)
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
 			return 1
 		}
 		args = flagSet.Args()
-		if len(args) == 0 {
-			fmt.Fprintf(eout, "Missing value\n")
+		switch {
+		case len(args) == 0:
+			fmt.Fprintf(eout, "Missing field\n")
+			return 1
+		case len(args) == 1 && removeAll == false:
+			fmt.Fprintf(eout, "Missing value, use -all to remove every %s tag\n", args[0])
 			return 1
 		}
 
-		s, err := namaste.Where(dName, args[0])
+		var names []string
+		switch {
+		case removeAll && dryRun:
+			names, err = namaste.FindAll(dName, args[0])
+		case removeAll:
+			names, err = namaste.RemoveAll(dName, args[0])
+		case dryRun:
+			names, err = namaste.FindValue(dName, args[0], args[1])
+		default:
+			names, err = namaste.Remove(dName, args[0], args[1])
+		}
 		if err != nil {
 			fmt.Fprintf(eout, "%s\n", err)
-			return 1
+			if len(names) == 0 {
+				return 1
+			}
 		}
-		if asJSON && s != "" {
-			m := map[string]string{
-				"where": s,
+		if asJSON {
+			m := map[string]interface{}{
+				"removed": names,
+				"dry_run": dryRun,
 			}
 			src, err := json.Marshal(m)
 			if err != nil {
@@ -369,12 +798,23 @@ func main() {
 				return 1
 			}
 			fmt.Fprintf(out, "%s\n", src)
+		} else if verbose || dryRun {
+			for _, name := range names {
+				if dryRun {
+					fmt.Fprintf(out, "would remove %s\n", name)
+				} else {
+					fmt.Fprintf(out, "removed %s\n", name)
+				}
+			}
 		}
-		if verbose && s != "" {
-			fmt.Fprintf(out, "%s\n", s)
+		if err != nil {
+			return 1
 		}
 		return 0
 	})
+	verb.SetParams("FIELD", "[VALUE]")
+	verb.BoolVar(&removeAll, "a,all", false, "remove every tag of the field")
+	verb.BoolVar(&dryRun, "n,dry-run", false, "list the tags without removing them")
 	verb.BoolVar(&asJSON, "j,json", false, "set json output")
 	verb.BoolVar(&verbose, "V,verbose", false, "set verbose output")
 
@@ -415,6 +855,44 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Set the filename encoding
+	if encodingName == "" {
+		encodingName = os.Getenv("NAMASTE_ENCODING")
+	}
+	if encodingName != "" {
+		if err := namaste.SetEncoding(encodingName); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Write ASCII only filenames
+	if transliterate || os.Getenv("NAMASTE_TRANSLITERATE") == "true" {
+		namaste.TransliterateNames = true
+	}
+
+	// Set the Unicode normalization
+	if normalizationForm == "" {
+		normalizationForm = os.Getenv("NAMASTE_NORMALIZE")
+	}
+	if normalizationForm != "" {
+		if err := namaste.SetNormalization(normalizationForm); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Add any type profiles
+	if profilesFName == "" {
+		profilesFName = os.Getenv("NAMASTE_PROFILES")
+	}
+	if profilesFName != "" {
+		if err := namaste.LoadTypeProfiles(profilesFName); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Application Logic
 	exitCode := app.Run(args)
 	if exitCode != 0 {
@@ -0,0 +1,179 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+)
+
+// versionOperators are the comparisons allowed in a type constraint,
+// longest first so "<=" is matched before "<".
+var versionOperators = []string{">=", "<=", "==", "!=", ">", "<", "="}
+
+// versionComparison is a single comparison such as ">=1.1"
+type versionComparison struct {
+	op      string
+	version *DirectoryType
+}
+
+// TypeConstraint matches directory types by name and version, e.g.
+// "dataset>=1.1,<2" matches "0=dataset_1.1" through "0=dataset_1.99"
+// but not "0=dataset_2.0". A constraint without comparisons, e.g.
+// "dataset", matches any version of the type.
+type TypeConstraint struct {
+	Name        string
+	comparisons []*versionComparison
+}
+
+// ParseTypeConstraint parses a type constraint, a type name followed
+// by zero or more comma separated comparisons using ">=", "<=", "==",
+// "=", "!=", ">" or "<", e.g. "dataset>=1.1,<2" or "bagit=1".
+func ParseTypeConstraint(s string) (*TypeConstraint, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexAny(s, "<>=!")
+	if i < 0 {
+		i = len(s)
+	}
+	c := &TypeConstraint{
+		Name: strings.TrimSpace(s[0:i]),
+	}
+	if c.Name == "" {
+		return nil, fmt.Errorf("%q is missing a type name", s)
+	}
+	if strings.ContainsAny(c.Name, ", \t") {
+		return nil, fmt.Errorf("%q is not a valid type name", c.Name)
+	}
+	if i == len(s) {
+		return c, nil
+	}
+	for _, part := range strings.Split(s[i:], ",") {
+		part = strings.TrimSpace(part)
+		cmp := new(versionComparison)
+		for _, op := range versionOperators {
+			if strings.HasPrefix(part, op) {
+				cmp.op = op
+				break
+			}
+		}
+		version := strings.TrimSpace(strings.TrimPrefix(part, cmp.op))
+		if cmp.op == "" || version == "" {
+			return nil, fmt.Errorf("%q is not a valid version comparison in %q", part, s)
+		}
+		cmp.version = ParseType(c.Name + "_" + version)
+		if cmp.version.Major == "" {
+			return nil, fmt.Errorf("%q is not a valid version in %q", version, s)
+		}
+		c.comparisons = append(c.comparisons, cmp)
+	}
+	return c, nil
+}
+
+// compareVersionPart compares two parts of a version. Leading digits
+// are compared numerically and any remainder as text, e.g. "9" < "10"
+// and "19" < "19b". A missing part counts as "0".
+func compareVersionPart(a, b string) int {
+	if a == "" {
+		a = "0"
+	}
+	if b == "" {
+		b = "0"
+	}
+	split := func(s string) (string, string) {
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		return strings.TrimLeft(s[0:i], "0"), s[i:]
+	}
+	aNum, aRest := split(a)
+	bNum, bRest := split(b)
+	switch {
+	case len(aNum) != len(bNum):
+		if len(aNum) < len(bNum) {
+			return -1
+		}
+		return 1
+	case aNum != bNum:
+		return strings.Compare(aNum, bNum)
+	}
+	return strings.Compare(aRest, bRest)
+}
+
+// compareVersions compares the major then minor versions of two types
+func compareVersions(a, b *DirectoryType) int {
+	if i := compareVersionPart(a.Major, b.Major); i != 0 {
+		return i
+	}
+	return compareVersionPart(a.Minor, b.Minor)
+}
+
+// Match returns true if a directory type satisfies the constraint. A
+// type without a version only matches constraints without comparisons.
+func (c *TypeConstraint) Match(t *DirectoryType) bool {
+	if t.Name != c.Name {
+		return false
+	}
+	if len(c.comparisons) > 0 && t.Major == "" {
+		return false
+	}
+	for _, cmp := range c.comparisons {
+		i := compareVersions(t, cmp.version)
+		ok := false
+		switch cmp.op {
+		case ">=":
+			ok = (i >= 0)
+		case "<=":
+			ok = (i <= 0)
+		case "==", "=":
+			ok = (i == 0)
+		case "!=":
+			ok = (i != 0)
+		case ">":
+			ok = (i > 0)
+		case "<":
+			ok = (i < 0)
+		}
+		if ok == false {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraint in the form it was parsed from
+func (c *TypeConstraint) String() string {
+	l := []string{}
+	for _, cmp := range c.comparisons {
+		l = append(l, cmp.op+cmp.version.Version())
+	}
+	return c.Name + strings.Join(l, ",")
+}
+
+// MatchTypes returns the types declared in a directory that satisfy
+// a constraint such as "dataset>=1.1,<2".
+func MatchTypes(dName, constraint string) ([]*DirectoryType, error) {
+	c, err := ParseTypeConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+	dirTypes, err := GetDirTypes(dName)
+	if err != nil {
+		return nil, err
+	}
+	results := []*DirectoryType{}
+	for _, t := range dirTypes {
+		if c.Match(t) {
+			results = append(results, t)
+		}
+	}
+	return results, nil
+}
+
+// HasType returns true if a directory declares a type satisfying a
+// constraint such as "dataset>=1.1,<2". See ParseTypeConstraint.
+func HasType(dName, constraint string) (bool, error) {
+	results, err := MatchTypes(dName, constraint)
+	if err != nil {
+		return false, err
+	}
+	return len(results) > 0, nil
+}
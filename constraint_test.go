@@ -0,0 +1,86 @@
+package namaste
+
+import (
+	"testing"
+)
+
+func TestTypeConstraint(t *testing.T) {
+	expected := map[string]map[string]bool{
+		"dataset>=1.1,<2": map[string]bool{
+			"dataset_1.1":  true,
+			"dataset_1.10": true,
+			"dataset_1.9":  true,
+			"dataset_1":    false,
+			"dataset_1.0":  false,
+			"dataset_2":    false,
+			"dataset_2.0":  false,
+			"dataset":      false,
+			"bagit_1.5":    false,
+		},
+		"dataset": map[string]bool{
+			"dataset":     true,
+			"dataset_0.1": true,
+			"datasets_1":  false,
+		},
+		"bagit=1": map[string]bool{
+			"bagit_1":    true,
+			"bagit_1.0":  true,
+			"bagit_01.0": true,
+			"bagit_1.1":  false,
+		},
+		"dflat > 0.19, != 0.20": map[string]bool{
+			"dflat_0.19":  false,
+			"dflat_0.19b": true,
+			"dflat_0.20":  false,
+			"dflat_0.100": true,
+		},
+		"ocfl_object<=1.0": map[string]bool{
+			"ocfl_object_1.0": true,
+			"ocfl_object_0.9": true,
+			"ocfl_object_1.1": false,
+		},
+	}
+	for s, types := range expected {
+		c, err := ParseTypeConstraint(s)
+		if err != nil {
+			t.Errorf("ParseTypeConstraint(%q) failed, %s", s, err)
+			continue
+		}
+		for val, expect := range types {
+			if result := c.Match(ParseType(val)); result != expect {
+				t.Errorf("%q matching %q, expected %t, got %t", s, val, expect, result)
+			}
+		}
+	}
+	for _, s := range []string{"", ">=1", "dataset>=", "dataset ~1", "dataset,bagit", "dataset>=a", "dataset>=1,"} {
+		if _, err := ParseTypeConstraint(s); err == nil {
+			t.Errorf("expected an error parsing %q", s)
+		}
+	}
+}
+
+func TestHasType(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("has-type-test")
+	dName := mem + "has-type-test"
+	DirType(dName, "dataset_1.2")
+	DirType(dName, "bagit_0.97")
+	expected := map[string]bool{
+		"dataset>=1.1,<2": true,
+		"dataset>=2":      false,
+		"bagit":           true,
+		"ocfl_object":     false,
+	}
+	for s, expect := range expected {
+		ok, err := HasType(dName, s)
+		if err != nil {
+			t.Errorf("HasType(%q, %q) failed, %s", dName, s, err)
+		}
+		if ok != expect {
+			t.Errorf("HasType(%q, %q) expected %t, got %t", dName, s, expect, ok)
+		}
+	}
+	if _, err := HasType(dName, "<2"); err == nil {
+		t.Errorf("expected an error for an invalid constraint")
+	}
+}
@@ -0,0 +1,162 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EncodingProfile is a named set of characters escaped as ^XX in tag
+// filenames. "^" and control characters are escaped by every profile
+// and all profiles decode the same way, so tags written with one
+// profile can be read with any other.
+type EncodingProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Chars are the characters escaped in addition to "^" and
+	// control characters
+	Chars string `json:"chars"`
+	// TrailingDots escapes periods at the end of a value, Windows
+	// drops trailing periods and spaces from filenames. (Names of
+	// devices such as CON or NUL can't occur as every tag filename
+	// starts with its label and "=".)
+	TrailingDots bool `json:"trailing_dots,omitempty"`
+	// ASCII escapes every byte outside ASCII so multibyte UTF-8
+	// characters are written as ^XX sequences
+	ASCII bool `json:"ascii,omitempty"`
+
+	table *[256]bool
+}
+
+var (
+	// NamasteEncoding escapes the characters listed in Section 4 of
+	// the Namaste Spec, it is the default.
+	NamasteEncoding = newEncodingProfile("namaste", "Namaste Spec, Section 4", " \"*/:<>?\\|", false, false)
+
+	// POSIXEncoding escapes only what POSIX filesystems require
+	POSIXEncoding = newEncodingProfile("posix", "POSIX filesystems, only / is escaped", "/", false, false)
+
+	// WindowsEncoding is safe for Windows filesystems and SMB shares
+	WindowsEncoding = newEncodingProfile("windows", "Windows filesystems, trailing periods are escaped too", " \"*/:<>?\\|", true, false)
+
+	// S3Encoding avoids the characters S3 object keys need special
+	// handling for
+	S3Encoding = newEncodingProfile("s3", "S3 and cloud object storage keys", " \"*/:<>?\\|&$@=;+,{}[]%~#`'", false, false)
+
+	// WebEncoding is safe for filenames published as URL paths
+	WebEncoding = newEncodingProfile("web", "URL paths served over HTTP", " \"*/:<>?\\|%#&+;=[]{}`'", false, false)
+
+	// ASCIIEncoding is the Namaste Spec's profile writing ASCII only
+	// filenames, characters outside ASCII are escaped as UTF-8 bytes
+	ASCIIEncoding = newEncodingProfile("ascii", "ASCII only, other characters are escaped as UTF-8 bytes", " \"*/:<>?\\|", false, true)
+
+	// encodingProfiles are the profiles in the order they are listed
+	encodingProfiles = []*EncodingProfile{
+		NamasteEncoding,
+		POSIXEncoding,
+		WindowsEncoding,
+		S3Encoding,
+		WebEncoding,
+		ASCIIEncoding,
+	}
+
+	// Encoding is the profile used by Encode and the setters
+	Encoding = NamasteEncoding
+)
+
+func newEncodingProfile(name, description, chars string, trailingDots, ascii bool) *EncodingProfile {
+	profile := &EncodingProfile{
+		Name:         name,
+		Description:  description,
+		Chars:        chars,
+		TrailingDots: trailingDots,
+		ASCII:        ascii,
+	}
+	profile.table = profile.escapeTable()
+	return profile
+}
+
+// escapeTable returns the table of bytes the profile escapes
+func (profile *EncodingProfile) escapeTable() *[256]bool {
+	table := newEscapeTable(profile.Chars)
+	if profile.ASCII {
+		for c := 0x80; c < 0x100; c++ {
+			table[c] = true
+		}
+	}
+	return table
+}
+
+// EncodeValue escapes a value for a filename using the profile.
+// Profiles not made by this package build their table on each call.
+func (profile *EncodingProfile) EncodeValue(s string) string {
+	table := profile.table
+	if table == nil {
+		table = profile.escapeTable()
+	}
+	s = encodeWith(table, s)
+	if profile.TrailingDots {
+		i := len(s)
+		for i > 0 && s[i-1] == '.' {
+			i--
+		}
+		s = s[0:i] + strings.Repeat("^2E", len(s)-i)
+	}
+	return s
+}
+
+// EncodingProfiles returns the named encoding profiles
+func EncodingProfiles() []*EncodingProfile {
+	return append([]*EncodingProfile{}, encodingProfiles...)
+}
+
+// LookupEncoding returns the encoding profile with the given name,
+// e.g. "windows"
+func LookupEncoding(name string) (*EncodingProfile, error) {
+	for _, profile := range encodingProfiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, nil
+		}
+	}
+	l := []string{}
+	for _, profile := range encodingProfiles {
+		l = append(l, profile.Name)
+	}
+	return nil, fmt.Errorf("unknown encoding %q, use %s", name, strings.Join(l, ", "))
+}
+
+// SetEncoding sets the profile used by Encode and the setters by name
+func SetEncoding(name string) error {
+	profile, err := LookupEncoding(name)
+	if err != nil {
+		return err
+	}
+	Encoding = profile
+	return nil
+}
+
+// covers returns true if profile escapes at least everything other
+// escapes
+func (profile *EncodingProfile) covers(other *EncodingProfile) bool {
+	if (other.TrailingDots && profile.TrailingDots == false) || (other.ASCII && profile.ASCII == false) {
+		return false
+	}
+	for i := 0; i < len(other.Chars); i++ {
+		if strings.IndexByte(profile.Chars, other.Chars[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isEncoded returns true if name is the filename the current Encoding,
+// or a profile escaping at least as much, gives tag and value. Tags
+// written by a stricter profile or with TransliterateNames are still
+// correctly encoded.
+func isEncoded(tag, value, name string) bool {
+	for _, profile := range encodingProfiles {
+		if profile.covers(Encoding) && (encodeName(profile, tag, value) == name || encodeASCIIName(profile, tag, value) == name) {
+			return true
+		}
+	}
+	return encodeName(Encoding, tag, value) == name
+}
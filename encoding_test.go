@@ -0,0 +1,72 @@
+package namaste
+
+import (
+	"testing"
+)
+
+func TestEncodingProfiles(t *testing.T) {
+	value := `Q&A: 50% of "Tom's" #1 hits/misses...`
+	expected := map[string]string{
+		"namaste": "2=Q&A^3A^2050%^20of^20^22Tom's^22^20#1^20hits^2Fmisses..^2E",
+		"posix":   `2=Q&A: 50% of "Tom's" #1 hits^2Fmisses..^2E`,
+		"windows": "2=Q&A^3A^2050%^20of^20^22Tom's^22^20#1^20hits^2Fmisses^2E^2E^2E",
+		"s3":      "2=Q^26A^3A^2050^25^20of^20^22Tom^27s^22^20^231^20hits^2Fmisses..^2E",
+		"web":     "2=Q^26A^3A^2050^25^20of^20^22Tom^27s^22^20^231^20hits^2Fmisses..^2E",
+		"ascii":   "2=Q&A^3A^2050%^20of^20^22Tom's^22^20#1^20hits^2Fmisses..^2E",
+	}
+	defer SetEncoding("namaste")
+	for _, profile := range EncodingProfiles() {
+		if err := SetEncoding(profile.Name); err != nil {
+			t.Errorf("SetEncoding(%q) failed, %s", profile.Name, err)
+			continue
+		}
+		name := Encode("what", value)
+		if name != expected[profile.Name] {
+			t.Errorf("%s expected %q, got %q", profile.Name, expected[profile.Name], name)
+		}
+		// Every profile decodes the same way
+		if result := Decode(name); result != value {
+			t.Errorf("%s decoding %q expected %q, got %q", profile.Name, name, value, result)
+		}
+	}
+	if err := SetEncoding("ntfs"); err == nil {
+		t.Errorf("expected an error for an unknown encoding")
+	}
+	if profile, err := LookupEncoding("Windows"); err != nil || profile != WindowsEncoding {
+		t.Errorf("expected the windows profile, got %+v, %v", profile, err)
+	}
+
+	// Trailing periods on Windows
+	if s := WindowsEncoding.EncodeValue("Hamlet."); s != "Hamlet^2E" {
+		t.Errorf("expected %q, got %q", "Hamlet^2E", s)
+	}
+
+	// Multibyte characters are escaped by the ascii profile
+	if s := ASCIIEncoding.EncodeValue("Gödel"); s != "G^C3^B6del" {
+		t.Errorf("expected %q, got %q", "G^C3^B6del", s)
+	}
+
+	// Filenames from a stricter profile are correctly encoded
+	SetEncoding("namaste")
+	if isEncoded("2", value, expected["web"]) == false || isEncoded("2", value, expected["posix"]) {
+		t.Errorf("expected web but not posix encoding to be accepted for the namaste profile")
+	}
+	SetEncoding("posix")
+	if isEncoded("2", value, expected["namaste"]) == false {
+		t.Errorf("expected namaste encoding to be accepted for the posix profile")
+	}
+}
+
+func TestEncodeValueConcurrent(t *testing.T) {
+	done := make(chan string)
+	for i := 0; i < 4; i++ {
+		go func() {
+			done <- ASCIIEncoding.EncodeValue("Gödel")
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if s := <-done; s != "G^C3^B6del" {
+			t.Errorf("expected %q, got %q", "G^C3^B6del", s)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ExtensionPrefix starts the name of locally defined namaste tags
+	// (e.g. x_ark, x_collection) so they won't conflict with tag names
+	// defined in future versions of the Namaste Spec. Used alone as a
+	// kind in Get or GetValues it matches all extension tags.
+	ExtensionPrefix = "x_"
+)
+
+// ExtensionName validates an extension tag name and returns it with
+// the ExtensionPrefix, e.g. "ark" and "x_ark" both return "x_ark".
+// The name after the prefix must be made up of letters, digits and
+// underscores.
+func ExtensionName(name string) (string, error) {
+	s := strings.TrimPrefix(name, ExtensionPrefix)
+	if s == "" {
+		return "", fmt.Errorf("missing extension name")
+	}
+	for _, c := range s {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' {
+			continue
+		}
+		return "", fmt.Errorf("%q is not a valid extension name, use letters, digits and underscores", name)
+	}
+	return ExtensionPrefix + s, nil
+}
+
+// isExtension returns true if name is a valid extension tag name
+func isExtension(name string) bool {
+	if strings.HasPrefix(name, ExtensionPrefix) == false {
+		return false
+	}
+	_, err := ExtensionName(name)
+	return err == nil
+}
+
+// Extension sets an extension tag (e.g. x_ark) on a directory.
+// The name may be given with or without the ExtensionPrefix.
+func Extension(dName, name, val string) (string, error) {
+	tag, err := ExtensionName(name)
+	if err != nil {
+		return "", err
+	}
+	return setNamaste(dName, tag, val)
+}
+
+// GetExtensions returns the extension tags of a directory. If names
+// is empty all extension tags are returned otherwise only those
+// matching the names given.
+func GetExtensions(dName string, names []string) ([]Tag, error) {
+	if len(names) == 0 {
+		names = []string{ExtensionPrefix}
+	}
+	kinds := []string{}
+	for _, name := range names {
+		if name == ExtensionPrefix {
+			kinds = append(kinds, name)
+			continue
+		}
+		tag, err := ExtensionName(name)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, tag)
+	}
+	return Get(dName, kinds)
+}
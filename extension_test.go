@@ -0,0 +1,103 @@
+package namaste
+
+import (
+	"testing"
+)
+
+func TestExtensionName(t *testing.T) {
+	expected := map[string]string{
+		"ark":          "x_ark",
+		"x_ark":        "x_ark",
+		"x_collection": "x_collection",
+		"embargo_2":    "x_embargo_2",
+	}
+	for name, expect := range expected {
+		result, err := ExtensionName(name)
+		if err != nil {
+			t.Errorf("ExtensionName(%q) failed, %s", name, err)
+		} else if result != expect {
+			t.Errorf("expected %q, got %q", expect, result)
+		}
+	}
+	for _, name := range []string{"", "x_", "x_a=b", "x_ark id", "x_ark/id"} {
+		if _, err := ExtensionName(name); err == nil {
+			t.Errorf("expected ExtensionName(%q) to fail", name)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	expected := map[string]string{
+		"x_ark=ark^3A^2F12345^2Ffk4abc": "ark:/12345/fk4abc",
+		"x_collection=Twain^20Papers":   "Twain Papers",
+		"x_embargo=2030-01-01":          "2030-01-01",
+	}
+	if _, err := Extension(testDir, "ark", "ark:/12345/fk4abc"); err != nil {
+		t.Errorf("Extension(%q, ark) failed, %s", testDir, err)
+	}
+	if _, err := Extension(testDir, "x_collection", "Twain Papers"); err != nil {
+		t.Errorf("Extension(%q, x_collection) failed, %s", testDir, err)
+	}
+	if _, err := Extension(testDir, "embargo", "2030-01-01"); err != nil {
+		t.Errorf("Extension(%q, embargo) failed, %s", testDir, err)
+	}
+	if _, err := Extension(testDir, "bad name", "2030-01-01"); err == nil {
+		t.Errorf("expected Extension(%q, %q) to fail", testDir, "bad name")
+	}
+	defer func() {
+		for name := range expected {
+			testStore.Remove(testPath, name)
+		}
+	}()
+
+	l, err := GetExtensions(testDir, nil)
+	if err != nil {
+		t.Errorf("GetExtensions(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	if len(l) != len(expected) {
+		t.Errorf("expected %d extensions, got %+v", len(expected), l)
+	}
+	for _, tag := range l {
+		if val, ok := expected[tag.Raw]; ok == false {
+			t.Errorf("unexpected %q", tag.Raw)
+		} else if tag.Value != val {
+			t.Errorf("expected %q, got %q", val, tag.Value)
+		}
+	}
+
+	// Filter by name
+	l, err = GetExtensions(testDir, []string{"ark"})
+	if err != nil {
+		t.Errorf("GetExtensions(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	if len(l) != 1 || l[0].Raw != "x_ark=ark^3A^2F12345^2Ffk4abc" {
+		t.Errorf("expected x_ark, got %+v", l)
+	}
+
+	// Get includes extensions by default
+	l, err = Get(testDir, nil)
+	if err != nil {
+		t.Errorf("Get(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	found := 0
+	for _, tag := range l {
+		if _, ok := expected[tag.Raw]; ok {
+			found++
+		}
+	}
+	if found != len(expected) {
+		t.Errorf("expected Get to include %d extensions, got %+v", len(expected), l)
+	}
+
+	values, err := GetValues(testDir, []string{"x_collection"})
+	if err != nil {
+		t.Errorf("GetValues(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	if len(values) != 1 || values[0].Value != "Twain Papers" {
+		t.Errorf("expected x_collection value, got %+v", values)
+	}
+}
@@ -0,0 +1,395 @@
+package namaste
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	gcsScope    = "https://www.googleapis.com/auth/devstorage.read_write"
+	gcsTokenURI = "https://oauth2.googleapis.com/token"
+)
+
+// GCSStore keeps namaste tags in Google Cloud Storage. Directory
+// names take the form BUCKET/PREFIX and tags are small objects named
+// PREFIX/TAG, e.g. "0=bagit_0.1".
+//
+// Credentials are found the same way as the Google Cloud tools,
+// GOOGLE_OAUTH_ACCESS_TOKEN holds an access token or
+// GOOGLE_APPLICATION_CREDENTIALS names a service account or
+// authorized user key file, falling back to the file created by
+// "gcloud auth application-default login". When STORAGE_EMULATOR_HOST
+// is set requests go to the emulator without credentials.
+type GCSStore struct {
+	// Endpoint is the base URL of the service, defaults to
+	// https://storage.googleapis.com
+	Endpoint string
+	// CredentialsFile is the service account or authorized user
+	// key file used to get access tokens
+	CredentialsFile string
+	// AccessToken is used as is when set
+	AccessToken string
+	// Anonymous requests are sent without credentials
+	Anonymous bool
+	// Client is the HTTP client used, defaults to http.DefaultClient
+	Client *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// gcsCredentials holds the fields of a service account or authorized
+// user key file
+type gcsCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// gcsObjects is the result of listing objects
+type gcsObjects struct {
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+	Prefixes      []string `json:"prefixes"`
+	NextPageToken string   `json:"nextPageToken"`
+}
+
+// gcsError is the error document returned by the JSON API
+type gcsError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewGCSStore returns a GCSStore configured from the environment
+func NewGCSStore() *GCSStore {
+	store := &GCSStore{
+		AccessToken:     os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+		CredentialsFile: os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"),
+	}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		if strings.Contains(host, "://") == false {
+			host = "http://" + host
+		}
+		store.Endpoint = host
+		store.Anonymous = true
+	}
+	if store.CredentialsFile == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			fName := path.Join(home, ".config", "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(fName); err == nil {
+				store.CredentialsFile = fName
+			}
+		}
+	}
+	return store
+}
+
+func init() {
+	RegisterStore("gs", NewGCSStore())
+}
+
+func (store *GCSStore) endpoint() string {
+	if store.Endpoint == "" {
+		return "https://storage.googleapis.com"
+	}
+	return strings.TrimSuffix(store.Endpoint, "/")
+}
+
+func (store *GCSStore) client() *http.Client {
+	if store.Client == nil {
+		return http.DefaultClient
+	}
+	return store.Client
+}
+
+// signJWT returns a signed JWT assertion for a service account
+func signJWT(cred *gcsCredentials, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(cred.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("can't decode private key for %s", cred.ClientEmail)
+	}
+	var key *rsa.PrivateKey
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		var ok bool
+		if key, ok = k.(*rsa.PrivateKey); ok == false {
+			return "", fmt.Errorf("private key for %s is not an RSA key", cred.ClientEmail)
+		}
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", err
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   cred.ClientEmail,
+		"scope": gcsScope,
+		"aud":   cred.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	enc := base64.RawURLEncoding
+	s := enc.EncodeToString(header) + "." + enc.EncodeToString(claims)
+	h := sha256.Sum256([]byte(s))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	if err != nil {
+		return "", err
+	}
+	return s + "." + enc.EncodeToString(sig), nil
+}
+
+// accessToken returns a cached access token, requesting a new one
+// from the token URI of the credentials file when it has expired.
+func (store *GCSStore) accessToken() (string, error) {
+	if store.AccessToken != "" {
+		return store.AccessToken, nil
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.token != "" && time.Now().Before(store.expires) {
+		return store.token, nil
+	}
+	if store.CredentialsFile == "" {
+		return "", fmt.Errorf("missing Google Cloud credentials, set GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	src, err := os.ReadFile(store.CredentialsFile)
+	if err != nil {
+		return "", err
+	}
+	cred := new(gcsCredentials)
+	if err := json.Unmarshal(src, cred); err != nil {
+		return "", fmt.Errorf("%s, %s", store.CredentialsFile, err)
+	}
+	if cred.TokenURI == "" {
+		cred.TokenURI = gcsTokenURI
+	}
+	form := url.Values{}
+	switch cred.Type {
+	case "service_account":
+		assertion, err := signJWT(cred, time.Now())
+		if err != nil {
+			return "", err
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	case "authorized_user":
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", cred.ClientID)
+		form.Set("client_secret", cred.ClientSecret)
+		form.Set("refresh_token", cred.RefreshToken)
+	default:
+		return "", fmt.Errorf("%s, unsupported credentials type %q", store.CredentialsFile, cred.Type)
+	}
+	res, err := store.client().PostForm(cred.TokenURI, form)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("can't get access token from %s, %s", cred.TokenURI, res.Status)
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	store.token = token.AccessToken
+	// Refresh a minute before the token expires
+	store.expires = time.Now().Add(time.Duration(token.ExpiresIn-60) * time.Second)
+	return store.token, nil
+}
+
+// do sends a request to the JSON API returning the response body.
+// A missing bucket or object is reported as fs.ErrNotExist.
+func (store *GCSStore) do(method, u, contentType string, src []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, u, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if store.Anonymous == false {
+		token, err := store.accessToken()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res, err := store.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &fs.PathError{Op: strings.ToLower(method), Path: req.URL.Path, Err: fs.ErrNotExist}
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		e := new(gcsError)
+		if json.Unmarshal(body, e) == nil && e.Error.Message != "" {
+			return nil, fmt.Errorf("%s %s, %s", method, req.URL.Path, e.Error.Message)
+		}
+		return nil, fmt.Errorf("%s %s, %s", method, req.URL.Path, res.Status)
+	}
+	return body, nil
+}
+
+// objectURL returns the JSON API URL for an object
+func (store *GCSStore) objectURL(bucket, key string) string {
+	return fmt.Sprintf("%s/storage/v1/b/%s/o/%s", store.endpoint(), url.PathEscape(bucket), url.PathEscape(key))
+}
+
+// Stat confirms the bucket exists, a prefix is always a directory
+func (store *GCSStore) Stat(dName string) (fs.FileInfo, error) {
+	bucket, prefix := splitBucket(dName)
+	if bucket == "" {
+		return nil, fmt.Errorf("missing bucket name")
+	}
+	if _, err := store.do(http.MethodGet, fmt.Sprintf("%s/storage/v1/b/%s", store.endpoint(), url.PathEscape(bucket)), "", nil); err != nil {
+		return nil, err
+	}
+	name := bucket
+	if prefix != "" {
+		name = path.Base(prefix)
+	}
+	return &dirInfo{name: name}, nil
+}
+
+// list calls fnName for each object and fnDir for each prefix
+// directly under a prefix, a page of results at a time
+func (store *GCSStore) list(dName string, fnName func(string) error, fnDir func(string) error) error {
+	bucket, prefix := splitBucket(dName)
+	if prefix != "" {
+		prefix += "/"
+	}
+	query := url.Values{}
+	query.Set("delimiter", "/")
+	query.Set("prefix", prefix)
+	query.Set("fields", "items(name),prefixes,nextPageToken")
+	for {
+		u := fmt.Sprintf("%s/storage/v1/b/%s/o?%s", store.endpoint(), url.PathEscape(bucket), query.Encode())
+		body, err := store.do(http.MethodGet, u, "", nil)
+		if err != nil {
+			return err
+		}
+		result := new(gcsObjects)
+		if err := json.Unmarshal(body, result); err != nil {
+			return err
+		}
+		for _, obj := range result.Items {
+			if name := strings.TrimPrefix(obj.Name, prefix); name != "" {
+				if err := fnName(name); err != nil {
+					return err
+				}
+			}
+		}
+		for _, p := range result.Prefixes {
+			if name := strings.Trim(strings.TrimPrefix(p, prefix), "/"); name != "" {
+				if err := fnDir(name); err != nil {
+					return err
+				}
+			}
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		query.Set("pageToken", result.NextPageToken)
+	}
+	return nil
+}
+
+// List returns the names of the objects directly under a prefix
+func (store *GCSStore) List(dName string) ([]string, error) {
+	names := []string{}
+	err := store.ListEach(dName, func(name string) error {
+		names = append(names, name)
+		return nil
+	})
+	return names, err
+}
+
+// ListEach calls fn with the name of each object directly under a
+// prefix, a page of results at a time
+func (store *GCSStore) ListEach(dName string, fn func(name string) error) error {
+	return store.list(dName, fn, func(string) error { return nil })
+}
+
+// Dirs returns the names of the prefixes directly under a prefix
+func (store *GCSStore) Dirs(dName string, followLinks bool) ([]string, error) {
+	dirs := []string{}
+	err := store.list(dName, func(string) error { return nil }, func(name string) error {
+		dirs = append(dirs, name)
+		return nil
+	})
+	return dirs, err
+}
+
+// ListDir returns the names of the objects and the prefixes directly
+// under a prefix from one listing
+func (store *GCSStore) ListDir(dName string, followLinks bool) ([]string, []string, error) {
+	names, dirs := []string{}, []string{}
+	err := store.list(dName, func(name string) error {
+		names = append(names, name)
+		return nil
+	}, func(name string) error {
+		dirs = append(dirs, name)
+		return nil
+	})
+	return names, dirs, err
+}
+
+// Read returns the contents of a tag object
+func (store *GCSStore) Read(dName, name string) ([]byte, error) {
+	bucket, prefix := splitBucket(dName)
+	return store.do(http.MethodGet, store.objectURL(bucket, objectKey(prefix, name))+"?alt=media", "", nil)
+}
+
+// Write creates or replaces a tag object
+func (store *GCSStore) Write(dName, name string, src []byte) error {
+	bucket, prefix := splitBucket(dName)
+	query := url.Values{}
+	query.Set("uploadType", "media")
+	query.Set("name", objectKey(prefix, name))
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", store.endpoint(), url.PathEscape(bucket), query.Encode())
+	_, err := store.do(http.MethodPost, u, "text/plain; charset=utf-8", src)
+	return err
+}
+
+// Remove deletes a tag object
+func (store *GCSStore) Remove(dName, name string) error {
+	bucket, prefix := splitBucket(dName)
+	_, err := store.do(http.MethodDelete, store.objectURL(bucket, objectKey(prefix, name)), "", nil)
+	return err
+}
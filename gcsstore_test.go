@@ -0,0 +1,220 @@
+package namaste
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGCS is a minimal Google Cloud Storage JSON API for testing. It
+// also acts as the OAuth token endpoint for service accounts.
+type fakeGCS struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+	key     *rsa.PublicKey
+	authErr string
+}
+
+func (s *fakeGCS) token(w http.ResponseWriter, r *http.Request) {
+	assertion := r.FormValue("assertion")
+	parts := strings.Split(assertion, ".")
+	if r.FormValue("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	h := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(s.key, crypto.SHA256, h[:], sig); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	fmt.Fprintf(w, `{"access_token":"test-token","expires_in":3600,"token_type":"Bearer"}`)
+}
+
+func (s *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		s.token(w, r)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		s.authErr = fmt.Sprintf("%s %s, unexpected authorization %q", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+	}
+	p := r.URL.Path
+	upload := strings.HasPrefix(p, "/upload")
+	p = strings.TrimPrefix(strings.TrimPrefix(p, "/upload"), "/storage/v1/b/")
+	parts := strings.SplitN(p, "/", 3)
+	objects, ok := s.buckets[parts[0]]
+	if ok == false {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":{"code":404,"message":"The specified bucket does not exist."}}`)
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		fmt.Fprintf(w, `{"name":%q}`, parts[0])
+	case len(parts) == 2 && upload && r.Method == http.MethodPost:
+		src, _ := io.ReadAll(r.Body)
+		objects[r.URL.Query().Get("name")] = src
+		fmt.Fprintf(w, `{"name":%q}`, r.URL.Query().Get("name"))
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		prefix, delimiter := r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter")
+		keys := []string{}
+		for k := range objects {
+			if strings.HasPrefix(k, prefix) && (delimiter == "" || strings.Contains(k[len(prefix):], delimiter) == false) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		// Return one object per page to exercise page tokens
+		result := gcsObjects{}
+		start := 0
+		if token := r.URL.Query().Get("pageToken"); token != "" {
+			start = sort.SearchStrings(keys, token)
+		}
+		if start < len(keys) {
+			result.Items = append(result.Items, struct {
+				Name string `json:"name"`
+			}{Name: keys[start]})
+			if start+1 < len(keys) {
+				result.NextPageToken = keys[start+1]
+			}
+		}
+		src, _ := json.Marshal(result)
+		w.Write(src)
+	case len(parts) == 3 && r.Method == http.MethodGet:
+		if src, ok := objects[parts[2]]; ok && r.URL.Query().Get("alt") == "media" {
+			w.Write(src)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"error":{"code":404,"message":"No such object: %s"}}`, parts[2])
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		if _, ok := objects[parts[2]]; ok == false {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(objects, parts[2])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestGCSStore(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Errorf("Can't generate key, %s", err)
+		t.FailNow()
+	}
+	fake := &fakeGCS{
+		buckets: map[string]map[string][]byte{
+			"staging": map[string][]byte{
+				"scans/item1/page1.tif": []byte("TIFF"),
+				"scans/item1/page2.tif": []byte("TIFF"),
+			},
+		},
+		key: &key.PublicKey,
+	}
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+
+	// Write a service account key file pointing at the fake server
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	cred, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "ingest@example.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})),
+		"token_uri":    ts.URL + "/token",
+	})
+	fName := path.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(fName, cred, 0600); err != nil {
+		t.Errorf("Can't write %q, %s", fName, err)
+		t.FailNow()
+	}
+	store := &GCSStore{
+		Endpoint:        ts.URL,
+		CredentialsFile: fName,
+	}
+	RegisterStore("gs", store)
+	defer RegisterStore("gs", NewGCSStore())
+
+	dName := "gs://staging/scans/item1"
+	if _, err := DirType(dName, "dflat_0.19"); err != nil {
+		t.Errorf("DirType(%q) failed, %s", dName, err)
+	}
+	if _, err := Who(dName, "Twain, Mark"); err != nil {
+		t.Errorf("Who(%q) failed, %s", dName, err)
+	}
+	if _, err := When(dName, "1884"); err != nil {
+		t.Errorf("When(%q) failed, %s", dName, err)
+	}
+	if src := fake.buckets["staging"]["scans/item1/1=Twain,^20Mark"]; string(src) != "Twain, Mark\n" {
+		t.Errorf("expected who object, got %q", src)
+	}
+
+	tags, err := Get(dName, nil)
+	if err != nil {
+		t.Errorf("Get(%q) failed, %s", dName, err)
+		t.FailNow()
+	}
+	expected := []string{"0=dflat_0.19", "1=Twain,^20Mark", "3=1884"}
+	if len(tags) != len(expected) {
+		t.Errorf("expected %d tags, got %+v", len(expected), tags)
+		t.FailNow()
+	}
+	for i, tag := range tags {
+		if tag.Raw != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], tag.Raw)
+		}
+	}
+	values, err := GetValues(dName, []string{"who"})
+	if err != nil || len(values) != 1 || values[0].Value != "Twain, Mark" {
+		t.Errorf("unexpected values %+v, %v", values, err)
+	}
+	types, err := GetTypes(dName)
+	if err != nil {
+		t.Errorf("GetTypes(%q) failed, %s", dName, err)
+	} else if m, ok := types["dflat"]; ok == false || m["minor"] != "19" {
+		t.Errorf("unexpected types %+v", types)
+	}
+
+	if err := store.Remove("staging/scans/item1", "3=1884"); err != nil {
+		t.Errorf("Remove() failed, %s", err)
+	}
+	if _, err := store.Read("staging/scans/item1", "3=1884"); err == nil {
+		t.Errorf("expected an error reading a removed tag")
+	}
+	if _, err := Get("gs://missing/item1", nil); err == nil {
+		t.Errorf("expected an error for a missing bucket")
+	}
+	if fake.authErr != "" {
+		t.Error(fake.authErr)
+	}
+}
+
+func TestGCSEmulator(t *testing.T) {
+	saveHost := os.Getenv("STORAGE_EMULATOR_HOST")
+	defer os.Setenv("STORAGE_EMULATOR_HOST", saveHost)
+	os.Setenv("STORAGE_EMULATOR_HOST", "localhost:4443")
+	store := NewGCSStore()
+	if store.Endpoint != "http://localhost:4443" || store.Anonymous == false {
+		t.Errorf("expected anonymous emulator store, got %+v", store)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/caltechlibrary/namaste
 
 go 1.16
 
-require github.com/caltechlibrary/cli v0.0.16
+require (
+	github.com/caltechlibrary/cli v0.0.16
+	golang.org/x/text v0.3.7
+)
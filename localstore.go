@@ -0,0 +1,161 @@
+package namaste
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+)
+
+// LocalStore keeps namaste tags on local disc
+type LocalStore struct{}
+
+// Stat returns the file info of a directory
+func (store *LocalStore) Stat(dName string) (fs.FileInfo, error) {
+	return os.Stat(dName)
+}
+
+// List returns the names of the entries in a directory
+func (store *LocalStore) List(dName string) ([]string, error) {
+	items, err := os.ReadDir(dName)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, item := range items {
+		names = append(names, item.Name())
+	}
+	return names, nil
+}
+
+// listBatchSize is the number of directory entries read at a time
+// by ListEach
+const listBatchSize = 1024
+
+// ListEach calls fn with the name of each entry in a directory. The
+// directory is read in batches so very large directories are not
+// loaded into memory at once. Names are in directory order.
+func (store *LocalStore) ListEach(dName string, fn func(name string) error) error {
+	dir, err := os.Open(dName)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	for {
+		items, err := dir.ReadDir(listBatchSize)
+		for _, item := range items {
+			if err := fn(item.Name()); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Dirs returns the names of the subdirectories of a directory
+func (store *LocalStore) Dirs(dName string, followLinks bool) ([]string, error) {
+	_, dirs, err := store.ListDir(dName, followLinks)
+	return dirs, err
+}
+
+// ListDir returns the names of the entries and the subdirectories of
+// a directory, reading it once
+func (store *LocalStore) ListDir(dName string, followLinks bool) ([]string, []string, error) {
+	items, err := os.ReadDir(dName)
+	if err != nil {
+		return nil, nil, err
+	}
+	names, dirs := []string{}, []string{}
+	for _, item := range items {
+		names = append(names, item.Name())
+		switch {
+		case item.IsDir():
+			dirs = append(dirs, item.Name())
+		case followLinks && item.Type()&fs.ModeSymlink != 0:
+			if info, err := os.Stat(path.Join(dName, item.Name())); err == nil && info.IsDir() {
+				dirs = append(dirs, item.Name())
+			}
+		}
+	}
+	return names, dirs, nil
+}
+
+// Read returns the contents of a tag file
+func (store *LocalStore) Read(dName, name string) ([]byte, error) {
+	return os.ReadFile(path.Join(dName, name))
+}
+
+// tempCount makes temporary filenames unique within a process
+var tempCount uint64
+
+// tempPrefix starts the name of a temporary file written by Write. It
+// holds no "=" so a temporary file left by a crash is never taken for
+// a tag.
+const tempPrefix = ".namaste-"
+
+// Write creates or replaces a tag file atomically. The contents are
+// written to a temporary file in the same directory, flushed to disc
+// and renamed over the tag file, so a crash or full disc leaves either
+// the old tag file or the new one, never a partial one.
+func (store *LocalStore) Write(dName, name string, src []byte) error {
+	var (
+		f     *os.File
+		tName string
+		err   error
+	)
+	for i := 0; i < 100; i++ {
+		tName = path.Join(dName, fmt.Sprintf("%s%d-%d.tmp", tempPrefix, os.Getpid(), atomic.AddUint64(&tempCount, 1)))
+		f, err = os.OpenFile(tName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0664)
+		if os.IsExist(err) == false {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(src)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cErr := f.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tName, path.Join(dName, name))
+	}
+	if err != nil {
+		os.Remove(tName)
+		return err
+	}
+	return syncDir(dName)
+}
+
+// syncDir flushes a directory's entries to disc so a rename is
+// durable before anything that depends on it, e.g. removing the old
+// filename of a tag. Systems that can't sync a directory are ignored.
+func syncDir(dName string) error {
+	dir, err := os.Open(dName)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	if err := dir.Sync(); err != nil && errors.Is(err, syscall.EINVAL) == false && runtime.GOOS != "windows" {
+		return err
+	}
+	return nil
+}
+
+// Remove deletes a tag file
+func (store *LocalStore) Remove(dName, name string) error {
+	return os.Remove(path.Join(dName, name))
+}
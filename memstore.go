@@ -0,0 +1,140 @@
+package namaste
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps namaste tags in memory. It is useful for testing
+// and for staging tags before writing them elsewhere.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	dirs map[string]map[string][]byte
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		dirs: map[string]map[string][]byte{},
+	}
+}
+
+// Mkdir creates a directory, along with any parents, in the store
+// if it doesn't exist
+func (store *MemoryStore) Mkdir(dName string) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for dName = path.Clean(dName); dName != "." && dName != "/"; dName = path.Dir(dName) {
+		if _, ok := store.dirs[dName]; ok == false {
+			store.dirs[dName] = map[string][]byte{}
+		}
+	}
+}
+
+// Stat returns the file info of a directory
+func (store *MemoryStore) Stat(dName string) (fs.FileInfo, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	dName = path.Clean(dName)
+	if _, ok := store.dirs[dName]; ok == false {
+		return nil, &fs.PathError{Op: "stat", Path: dName, Err: fs.ErrNotExist}
+	}
+	return &dirInfo{name: path.Base(dName)}, nil
+}
+
+// List returns the names of the entries in a directory in
+// lexical order
+func (store *MemoryStore) List(dName string) ([]string, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	dName = path.Clean(dName)
+	dir, ok := store.dirs[dName]
+	if ok == false {
+		return nil, &fs.PathError{Op: "open", Path: dName, Err: fs.ErrNotExist}
+	}
+	names := []string{}
+	for name := range dir {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Dirs returns the names of the subdirectories of a directory in
+// lexical order
+func (store *MemoryStore) Dirs(dName string, followLinks bool) ([]string, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	dName = path.Clean(dName)
+	if _, ok := store.dirs[dName]; ok == false {
+		return nil, &fs.PathError{Op: "open", Path: dName, Err: fs.ErrNotExist}
+	}
+	names := []string{}
+	for key := range store.dirs {
+		if key != dName && path.Dir(key) == dName {
+			names = append(names, path.Base(key))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListDir returns the names of the entries and the subdirectories of
+// a directory in lexical order
+func (store *MemoryStore) ListDir(dName string, followLinks bool) ([]string, []string, error) {
+	names, err := store.List(dName)
+	if err != nil {
+		return nil, nil, err
+	}
+	dirs, err := store.Dirs(dName, followLinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return names, dirs, nil
+}
+
+// Read returns the contents of a tag file
+func (store *MemoryStore) Read(dName, name string) ([]byte, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+	dName = path.Clean(dName)
+	if dir, ok := store.dirs[dName]; ok {
+		if src, ok := dir[name]; ok {
+			return append([]byte{}, src...), nil
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: path.Join(dName, name), Err: fs.ErrNotExist}
+}
+
+// Write creates or replaces a tag file
+func (store *MemoryStore) Write(dName, name string, src []byte) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	dName = path.Clean(dName)
+	dir, ok := store.dirs[dName]
+	if ok == false {
+		return &fs.PathError{Op: "open", Path: path.Join(dName, name), Err: fs.ErrNotExist}
+	}
+	if name == "" || name == "." || name == ".." || path.Base(name) != name {
+		return fmt.Errorf("%q is not a valid filename", name)
+	}
+	dir[name] = append([]byte{}, src...)
+	return nil
+}
+
+// Remove deletes a tag file
+func (store *MemoryStore) Remove(dName, name string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	dName = path.Clean(dName)
+	if dir, ok := store.dirs[dName]; ok {
+		if _, ok := dir[name]; ok {
+			delete(dir, name)
+			return nil
+		}
+	}
+	return &fs.PathError{Op: "remove", Path: path.Join(dName, name), Err: fs.ErrNotExist}
+}
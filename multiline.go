@@ -0,0 +1,64 @@
+package namaste
+
+import (
+	"strings"
+)
+
+// isMultiLine returns true if a value holds a line break
+func isMultiLine(s string) bool {
+	return strings.ContainsAny(s, "\r\n")
+}
+
+// summaryLine returns the first line of a value holding more than
+// white space, trimmed. It is used in the filename of a multi-line
+// value.
+func summaryLine(s string) string {
+	for _, line := range strings.FieldsFunc(s, func(r rune) bool { return r == '\r' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// truncateSummary is truncate for the summary of a multi-line value,
+// the result always ends with the hash of the value and Ellipsis as
+// the full value is only in the tag file's contents
+func truncateSummary(prefix, s, value string, trailingDots bool) string {
+	return shorten(prefix, escapeEllipsis(s), value, trailingDots)
+}
+
+// fitName returns prefix and the value encoded for profile, truncated
+// to fit in MaxNameLength. A multi-line value is summarized by its
+// first line so filenames never hold a line break.
+func fitName(profile *EncodingProfile, prefix, value string, encode func(string) string) string {
+	if isMultiLine(value) {
+		return prefix + truncateSummary(prefix, encode(summaryLine(value)), value, profile.TrailingDots)
+	}
+	return prefix + truncate(prefix, encode(value), value, profile.TrailingDots)
+}
+
+// terminateValue returns the contents written to a tag file for a
+// value. A single LF is appended, or CRLF for a value ending in CR,
+// so trimNewline returns the value exactly, including any line breaks
+// at its end.
+func terminateValue(s string) string {
+	if strings.HasSuffix(s, "\r") {
+		return s + "\r\n"
+	}
+	return s + "\n"
+}
+
+// isTruncationOf returns true if name is a truncated or summarized
+// filename for a tag whose full value is contents. A hash in the name
+// must match contents.
+func isTruncationOf(tag, contents, name string) bool {
+	visible := strings.TrimSuffix(Decode(name), Ellipsis)
+	_, hash, _ := splitTruncated(name)
+	if hash == "" || hash == valueHash(contents) || hash == valueHash(NormalizeValue(contents)) {
+		if strings.HasPrefix(contents, visible) || strings.HasPrefix(summaryLine(contents), visible) {
+			return true
+		}
+	}
+	return isEncoded(tag, contents, name) || isTransliteration(tag, contents, name)
+}
@@ -0,0 +1,141 @@
+package namaste
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMultiLineNames(t *testing.T) {
+	// Summaries end with a hash of the value and Ellipsis
+	summaries := map[string]string{
+		"Abstract\nThe full text.": "note=Abstract",
+		"\n\n  Title  \r\nBody":    "note=Title",
+		"Line one\r\nLine two":     "note=Line^20one",
+		"Wait...\nmore":            "note=Wait..^2E",
+		"\n\n":                     "note=",
+		"Ends in a newline\n":      "note=Ends^20in^20a^20newline",
+	}
+	expected := map[string]string{
+		"Single line, no break":            "note=Single^20line,^20no^20break",
+		strings.Repeat("long ", 60) + "\n": "",
+	}
+	for val, expect := range summaries {
+		expected[val] = expect + hashMarker + valueHash(val) + Ellipsis
+	}
+	for val, expect := range expected {
+		name := Encode("note", val)
+		if strings.ContainsAny(name, "\r\n") {
+			t.Errorf("Encode(%q) holds a line break, %q", val, name)
+		}
+		if len(name) > MaxNameLength {
+			t.Errorf("%q is longer than %d bytes", name, MaxNameLength)
+		}
+		if expect != "" && name != expect {
+			t.Errorf("Encode(%q) expected %q, got %q", val, expect, name)
+		}
+	}
+}
+
+func TestMultiLineWindowsNames(t *testing.T) {
+	SetEncoding("windows")
+	defer SetEncoding("namaste")
+	values := []string{"Abstract\nThe full text.", "\n\n", "Wait...\nmore"}
+	for _, val := range values {
+		name := Encode("note", val)
+		if strings.HasSuffix(name, ".") || strings.HasSuffix(name, escapedEllipsis) == false {
+			t.Errorf("Encode(%q) expected to end with %q, got %q", val, escapedEllipsis, name)
+		}
+		if IsTruncated(name) == false || strings.HasSuffix(Decode(name), Ellipsis) == false {
+			t.Errorf("expected %q to be read as summarized, got %q", name, Decode(name))
+		}
+	}
+}
+
+func TestMultiLineValues(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("multiline-test")
+	dName := mem + "multiline-test"
+	values := []string{
+		"Abstract\n\nFirst paragraph.\n\nSecond paragraph.",
+		"Ends in a newline\n",
+		"Ends in a carriage return\r",
+		"Windows\r\nline endings\r\n",
+		"Plain value",
+	}
+	for _, val := range values {
+		name, err := Note(dName, val)
+		if err != nil {
+			t.Fatalf("Note(%q) failed, %s", val, err)
+		}
+		contents, err := readNamaste(dName, name)
+		if err != nil || contents != val {
+			t.Errorf("expected %q read back, got %q, %v", val, contents, err)
+		}
+	}
+
+	results, err := GetValues(dName, []string{"note"})
+	if err != nil {
+		t.Fatalf("GetValues() failed, %s", err)
+	}
+	found := map[string]bool{}
+	for _, val := range results {
+		found[val.Value] = true
+		if val.Mismatch {
+			t.Errorf("%q reported as a mismatch", val.Name)
+		}
+	}
+	for _, val := range values {
+		if found[val] == false {
+			t.Errorf("expected GetValues() to return %q", val)
+		}
+	}
+
+	report, err := Validate(dName)
+	if err != nil {
+		t.Fatalf("Validate() failed, %s", err)
+	}
+	if len(report.Findings) != 0 {
+		t.Errorf("expected no findings, got %+v", report.Findings)
+	}
+	plan, err := PlanRepair(dName, FromContents)
+	if err != nil || len(plan.Actions) != 0 {
+		t.Errorf("expected nothing to repair, got %+v, %v", plan, err)
+	}
+
+	// A line break escaped in the filename by older versions is renamed
+	store.Mkdir("multiline-repair")
+	store.Write("multiline-repair", "note=One^0ATwo", []byte("One\nTwo\n"))
+	plan, err = PlanRepair(mem+"multiline-repair", FromContents)
+	target := "note=One" + hashMarker + valueHash("One\nTwo") + Ellipsis
+	if err != nil || len(plan.Actions) != 1 || plan.Actions[0].Target != target {
+		t.Errorf("expected a rename to %q, got %+v, %v", target, plan, err)
+	}
+}
+
+func TestMultiLineSameSummary(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("item1")
+	dName := mem + "item1"
+	values := []string{
+		"Abstract\n\nFirst draft.",
+		"Abstract\n\nSecond draft.",
+	}
+	for _, val := range values {
+		if _, err := Note(dName, val); err != nil {
+			t.Fatalf("Note(%q) failed, %s", val, err)
+		}
+	}
+	tags, err := Get(dName, []string{"note"})
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("Get() expected two notes, got %+v, %v", tags, err)
+	}
+	found := map[string]bool{}
+	for _, tag := range tags {
+		found[tag.Value] = true
+	}
+	for _, val := range values {
+		if found[val] == false {
+			t.Errorf("expected a note holding %q, got %+v", val, tags)
+		}
+	}
+}
@@ -20,8 +20,7 @@ package namaste
 
 import (
 	"fmt"
-	"os"
-	"path"
+	"sort"
 	"strings"
 )
 
@@ -35,56 +34,163 @@ var (
 	}
 )
 
+// Encode returns the namaste filename for tag and value using the
+// current Encoding profile and Normalization. Filenames longer than
+// MaxNameLength are truncated and end with a short hash of the value
+// and Ellipsis, so values sharing a prefix get different filenames. A
+// multi-line value is summarized by its first line followed by
+// Ellipsis, the full value is kept in the tag file's contents.
 func Encode(tag, value string) string {
+	return encodeName(Encoding, tag, value)
+}
+
+// encodeName returns the namaste filename for tag and value using
+// an encoding profile
+func encodeName(profile *EncodingProfile, tag, value string) string {
+	if TransliterateNames {
+		return encodeASCIIName(profile, tag, value)
+	}
+	return fitName(profile, namePrefix(tag), NormalizeValue(value), profile.EncodeValue)
+}
+
+// encodeASCIIName returns an ASCII only filename for tag and value,
+// see TransliterateNames
+func encodeASCIIName(profile *EncodingProfile, tag, value string) string {
+	return fitName(profile, namePrefix(tag), NormalizeValue(value), func(s string) string {
+		return encodeWith(nonASCIITable, profile.EncodeValue(Transliterate(s)))
+	})
+}
+
+// namePrefix returns the start of a filename for a tag, e.g. "1="
+// for "who"
+func namePrefix(tag string) string {
 	if s, ok := normalizeFieldName[strings.ToLower(tag)]; ok == true {
 		tag = s
 	}
-	return fmt.Sprintf("%s=%s", tag, charEncode(value))
+	return fmt.Sprintf("%s=", tag)
 }
 
+// Decode returns the value encoded in a namaste filename. If the
+// filename was truncated the value returned ends with Ellipsis, the
+// full value is found in the tag file's contents (see GetValues).
 func Decode(value string) string {
-	for _, prefix := range []string{"0=", "1=", "2=", "3=", "4="} {
-		if strings.HasPrefix(value, prefix) {
-			return charDecode(value[2:])
+	if tag, err := ParseTag(value); err == nil {
+		return tag.Value
+	}
+	return untruncate(value)
+}
+
+// IsTruncated returns true if a namaste filename was truncated.
+func IsTruncated(name string) bool {
+	return isTruncated(name)
+}
+
+// defaultKinds are the tags returned by Get when no kinds are given
+var defaultKinds = []string{"0", "1", "2", "3", "4", "note", ExtensionPrefix}
+
+// normalizeKinds returns a copy of kinds with human names converted
+// to tag numbers, e.g. "who" becomes "1". Empty kinds returns the
+// defaultKinds.
+func normalizeKinds(kinds []string) []string {
+	if len(kinds) == 0 {
+		return defaultKinds
+	}
+	l := make([]string, len(kinds))
+	for i, kind := range kinds {
+		if s, ok := normalizeFieldName[strings.ToLower(kind)]; ok == true {
+			kind = s
 		}
+		l[i] = kind
 	}
-	return charDecode(value)
+	return l
 }
 
-func getNamaste(dName, tag string) ([]string, error) {
-	prefix := Encode(tag, "")
+// matchKind returns the index of the first kind matching the
+// directory entry name or -1 if there is no match.
+func matchKind(kinds []string, prefixes []string, name string) int {
+	for i, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			if kinds[i] == ExtensionPrefix {
+				if label, _, ok := splitNamaste(name); ok == false || isExtension(label) == false {
+					continue
+				}
+			}
+			return i
+		}
+	}
+	return -1
+}
 
-	results := []string{}
-	dInfo, err := os.Stat(dName)
+// getNamaste returns the namaste filenames in a directory matching
+// the normalized kinds. The directory is listed once, a batch at a
+// time when the store supports it. Results are grouped in the order
+// of kinds then sorted by name.
+func getNamaste(dName string, kinds []string) ([]string, error) {
+	prefixes := make([]string, len(kinds))
+	for i, kind := range kinds {
+		prefixes[i] = Encode(kind, "")
+		if kind == ExtensionPrefix {
+			prefixes[i] = ExtensionPrefix
+		}
+	}
+	store, dName, err := OpenStore(dName)
 	if err != nil {
 		return nil, err
 	}
-	if dInfo.IsDir() == false {
-		return nil, fmt.Errorf("expected %q to be a directory", dName)
+	if err := checkDir(store, dName); err != nil {
+		return nil, err
 	}
-	items, err := os.ReadDir(dName)
+	found := make([][]string, len(kinds))
+	err = listEach(store, dName, func(name string) error {
+		if i := matchKind(kinds, prefixes, name); i >= 0 {
+			found[i] = append(found[i], name)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	for _, item := range items {
-		name := item.Name()
-		if strings.HasPrefix(name, prefix) {
-			results = append(results, name)
-		}
+	results := []string{}
+	for _, l := range found {
+		sort.Strings(l)
+		results = append(results, l...)
 	}
 	return results, nil
 }
 
-func setNamaste(dName, tag, value string) (string, error) {
-	dInfo, err := os.Stat(dName)
+// readNamaste reads the contents of a namaste tag file, trimming the
+// terminal newline (LF, CR or CRLF) per Section 6 of the Namaste Spec.
+func readNamaste(dName, name string) (string, error) {
+	store, dName, err := OpenStore(dName)
+	if err != nil {
+		return "", err
+	}
+	src, err := store.Read(dName, name)
 	if err != nil {
 		return "", err
 	}
-	if dInfo.IsDir() == false {
-		return "", fmt.Errorf("%q is not a directory", dName)
+	return trimNewline(string(src)), nil
+}
+
+// trimNewline removes a single terminal LF, CR or CRLF
+func trimNewline(s string) string {
+	switch {
+	case strings.HasSuffix(s, "\r\n"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "\n"), strings.HasSuffix(s, "\r"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// setNamaste adds a tag to a directory, see SetValue to replace a
+// field's value
+func setNamaste(dName, tag, value string) (string, error) {
+	result, err := setTag(dName, tag, value, AddValue)
+	if err != nil {
+		return "", err
 	}
-	sNamaste := Encode(tag, value)
-	return sNamaste, os.WriteFile(path.Join(dName, sNamaste), []byte(value+"\n"), 0664)
+	return result.Filename, nil
 }
 
 func DirType(dName, val string) (string, error) {
@@ -111,65 +217,98 @@ func Note(dName, val string) (string, error) {
 	return setNamaste(dName, "note", val)
 }
 
-func Get(dName string, kinds []string) ([]string, error) {
-	if len(kinds) == 0 {
-		kinds = []string{"0", "1", "2", "3", "4", "note"}
-	} else {
-		// Convert to numeric string from human text, e.g. type, who, when
-		for i, val := range kinds {
-			if s, ok := normalizeFieldName[strings.ToLower(val)]; ok == true {
-				kinds[i] = s
-			}
-		}
+// Get returns the namaste tags of a directory parsed from their
+// filenames. The full value of a truncated tag is read from its
+// contents. When TransliterateNames is set a filename that is the
+// transliteration of its contents returns the contents as the value.
+// kinds limits the tags returned, e.g. "who", "1", "note" or "x_ark".
+// If kinds is empty all tags are returned.
+func Get(dName string, kinds []string) ([]Tag, error) {
+	l, err := getNamaste(dName, normalizeKinds(kinds))
+	if err != nil {
+		return nil, err
 	}
-	results := []string{}
-	for _, kind := range kinds {
-		l, err := getNamaste(dName, kind)
+	return readValues(dName, parseTags(l))
+}
+
+// readValues sets the value of tags whose filename doesn't hold the
+// exact value to the contents of the tag file, see Get
+func readValues(dName string, tags []Tag) ([]Tag, error) {
+	tags, err := readTruncated(dName, tags)
+	if err != nil {
+		return nil, err
+	}
+	return readTransliterated(dName, tags)
+}
+
+// Value holds a namaste tag along with the value read from its file.
+// Value is taken from the file contents, falling back to the decoded
+// filename when the file is empty. Decoded is the value recovered from
+// the filename and Mismatch is true when the two disagree. Truncated
+// is true when the filename holds a shortened value.
+type Value struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Decoded   string `json:"decoded"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Mismatch  bool   `json:"mismatch,omitempty"`
+}
+
+// GetValues returns the namaste tags of a directory with their values
+// read from the tag file contents. kinds works the same as in Get.
+func GetValues(dName string, kinds []string) ([]*Value, error) {
+	results := []*Value{}
+	l, err := getNamaste(dName, normalizeKinds(kinds))
+	if err != nil {
+		return results, err
+	}
+	for _, name := range l {
+		val := &Value{
+			Name:      name,
+			Decoded:   Decode(name),
+			Truncated: isTruncated(name),
+		}
+		s, err := readNamaste(dName, name)
 		if err != nil {
 			return results, err
 		}
-		if len(l) > 0 {
-			results = append(results, l...)
+		switch {
+		case s == "":
+			val.Value = val.Decoded
+		case val.Truncated:
+			val.Value = s
+			label, _, _ := splitNamaste(name)
+			val.Mismatch = (isTruncationOf(label, s, name) == false)
+		default:
+			label, _, _ := splitNamaste(name)
+			val.Value = s
+			val.Mismatch = (s != val.Decoded && isTransliteration(label, s, name) == false)
 		}
+		results = append(results, val)
 	}
 	return results, nil
 }
 
+// GetTypes returns the types of a directory keyed by type name, each
+// a map holding "name" and, when declared, "major" and "minor". Only
+// one version of each type is kept, use GetDirTypes to get them all.
 func GetTypes(dName string) (map[string]map[string]string, error) {
-	typeTags, err := getNamaste(dName, "0")
+	dirTypes, err := GetDirTypes(dName)
 	if err != nil {
 		return nil, err
 	}
 	types := map[string]map[string]string{}
-	var (
-		name    string
-		version []string
-	)
-	for _, t := range typeTags {
-		s := strings.SplitN(strings.TrimPrefix(t, "0="), "_", 2)
-		name = s[0]
-		if len(s) > 1 {
-			version = strings.SplitN(s[1], ".", 2)
-		} else {
-			version = []string{}
+	for _, t := range dirTypes {
+		m := map[string]string{
+			"name": t.Name,
 		}
-		switch len(version) {
-		case 2:
-			types[name] = map[string]string{
-				"name":  name,
-				"major": version[0],
-				"minor": version[1],
-			}
-		case 1:
-			types[name] = map[string]string{
-				"name":  name,
-				"major": version[1],
-			}
-		default:
-			types[name] = map[string]string{
-				"name": name,
-			}
+		if t.Major != "" {
+			m["major"] = t.Major
+		}
+		if t.Minor != "" {
+			m["minor"] = t.Minor
 		}
+		types[t.Name] = m
 	}
 	return types, nil
 }
@@ -21,86 +21,26 @@ package namaste
 import (
 	"bytes"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"os"
-	"path"
 	"strings"
 	"testing"
 )
 
 const (
-	testDir = "namaste-test"
+	testPath = "namaste-test"
+	testDir  = "mem://" + testPath
 )
 
-func copyDir(srcName string, destName string) (err error) {
-	srcInfo, err := os.Stat(srcName)
-	if err != nil {
-		return err
-	}
-
-	err = os.MkdirAll(destName, srcInfo.Mode())
-	if err != nil {
-		return err
-	}
-
-	dp, err := os.Open(srcName)
-	if err != nil {
-		return err
-	}
-	defer dp.Close()
-
-	items, err := dp.Readdir(-1)
-	for _, item := range items {
-
-		srcPath := path.Join(srcName, item.Name())
-		destPath := path.Join(destName, item.Name())
-
-		if item.IsDir() {
-			err = copyDir(srcPath, destPath)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = copyFile(srcPath, destPath)
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-func copyFile(srcName string, destName string) (err error) {
-	srcFile, err := os.Open(srcName)
-	if err != nil {
-		return err
-	}
-	defer srcFile.Close()
-
-	destFile, err := os.Create(destName)
-	if err != nil {
-		return err
-	}
-	defer destFile.Close()
-
-	_, err = io.Copy(destFile, srcFile)
-	if err == nil {
-		srcInfo, err := os.Stat(srcName)
-		if err != nil {
-			err = os.Chmod(destName, srcInfo.Mode())
-		}
-	}
-	return err
-}
+var (
+	testStore = NewMemoryStore()
+)
 
 func TestType(t *testing.T) {
 	// Setup this test
 	data := "bagit_0.1"
 	dataName := fmt.Sprintf("0=%s", data)
-	fName := path.Join(testDir, dataName)
-	os.RemoveAll(fName)
+	testStore.Remove(testPath, dataName)
 
 	// Test method
 	_, err := DirType(testDir, data)
@@ -111,15 +51,15 @@ func TestType(t *testing.T) {
 
 	// Validate the result
 	expected := []byte(data + "\n")
-	src, err := ioutil.ReadFile(fName)
+	src, err := testStore.Read(testPath, dataName)
 	if err != nil {
-		t.Errorf("Missing %q, %s", fName, err)
+		t.Errorf("Missing %q, %s", dataName, err)
 		t.FailNow()
 	}
 	if bytes.Equal(src, expected) == false {
 		t.Errorf("expected %q, got %q", expected, src)
 	} else {
-		os.RemoveAll(fName)
+		testStore.Remove(testPath, dataName)
 	}
 }
 
@@ -127,23 +67,22 @@ func TestWho(t *testing.T) {
 	// Setup this test
 	data := "Fayman,R."
 	dataName := fmt.Sprintf("1=%s", data)
-	fName := path.Join(testDir, dataName)
-	os.RemoveAll(fName)
+	testStore.Remove(testPath, dataName)
 
 	// Test Method
 	Who(testDir, data)
 
 	// Validate the result
 	expected := []byte(data + "\n")
-	src, err := ioutil.ReadFile(fName)
+	src, err := testStore.Read(testPath, dataName)
 	if err != nil {
-		t.Errorf("Missing %q, %s", fName, err)
+		t.Errorf("Missing %q, %s", dataName, err)
 		t.FailNow()
 	}
 	if bytes.Equal(src, expected) == false {
 		t.Errorf("expected %q, got %q", expected, src)
 	} else {
-		os.RemoveAll(fName)
+		testStore.Remove(testPath, dataName)
 	}
 }
 
@@ -151,23 +90,22 @@ func TestWhat(t *testing.T) {
 	// Setup this test
 	data := "Particles"
 	dataName := fmt.Sprintf("2=%s", data)
-	fName := path.Join(testDir, dataName)
-	os.RemoveAll(fName)
+	testStore.Remove(testPath, dataName)
 
 	// Test Method
 	What(testDir, data)
 
 	// Validate the result
 	expected := []byte(data + "\n")
-	src, err := ioutil.ReadFile(fName)
+	src, err := testStore.Read(testPath, dataName)
 	if err != nil {
-		t.Errorf("Missing %q, %s", fName, err)
+		t.Errorf("Missing %q, %s", dataName, err)
 		t.FailNow()
 	}
 	if bytes.Equal(src, expected) == false {
 		t.Errorf("expected %q, got %q", expected, src)
 	} else {
-		os.RemoveAll(fName)
+		testStore.Remove(testPath, dataName)
 	}
 }
 
@@ -175,23 +113,22 @@ func TestWhen(t *testing.T) {
 	// Setup this test
 	data := "2018"
 	dataName := fmt.Sprintf("3=%s", data)
-	fName := path.Join(testDir, dataName)
-	os.RemoveAll(fName)
+	testStore.Remove(testPath, dataName)
 
 	// Test Method
 	When(testDir, data)
 
 	// Validate the result
 	expected := []byte(data + "\n")
-	src, err := ioutil.ReadFile(fName)
+	src, err := testStore.Read(testPath, dataName)
 	if err != nil {
-		t.Errorf("Missing %q, %s", fName, err)
+		t.Errorf("Missing %q, %s", dataName, err)
 		t.FailNow()
 	}
 	if bytes.Equal(src, expected) == false {
 		t.Errorf("expected %q, got %q", expected, src)
 	} else {
-		os.RemoveAll(fName)
+		testStore.Remove(testPath, dataName)
 	}
 }
 
@@ -199,23 +136,22 @@ func TestWhere(t *testing.T) {
 	// Setup this test
 	data := "Pasadena"
 	dataName := fmt.Sprintf("4=%s", data)
-	fName := path.Join(testDir, dataName)
-	os.RemoveAll(fName)
+	testStore.Remove(testPath, dataName)
 
 	// Test Method
 	Where(testDir, data)
 
 	// Validate the result
 	expected := []byte(data + "\n")
-	src, err := ioutil.ReadFile(fName)
+	src, err := testStore.Read(testPath, dataName)
 	if err != nil {
-		t.Errorf("Missing %q, %s", fName, err)
+		t.Errorf("Missing %q, %s", dataName, err)
 		t.FailNow()
 	}
 	if bytes.Equal(src, expected) == false {
 		t.Errorf("expected %q, got %q", expected, src)
 	} else {
-		os.RemoveAll(fName)
+		testStore.Remove(testPath, dataName)
 	}
 }
 
@@ -243,7 +179,7 @@ func TestGet(t *testing.T) {
 	for key, val := range expected {
 		foundIt := false
 		for _, tagVal := range tags {
-			if tagVal == val {
+			if tagVal.Raw == val {
 				foundIt = true
 				break
 			}
@@ -268,15 +204,65 @@ func TestGet(t *testing.T) {
 		t.Errorf("expected length 1, got %d - %+v", len(l), l)
 		cleanOK = false
 	}
-	if l[0] != "1=Feynman,R." {
-		t.Errorf("expected '1=Feynman,R.', got %q", l[0])
+	if l[0].Raw != "1=Feynman,R." {
+		t.Errorf("expected '1=Feynman,R.', got %q", l[0].Raw)
 		cleanOK = false
 	}
 
 	// Cleanup after test
 	if cleanOK {
 		for key, _ := range expected {
-			os.RemoveAll(path.Join(testDir, key))
+			testStore.Remove(testPath, key)
+		}
+	}
+}
+
+func TestGetValues(t *testing.T) {
+	// Setup some test data, one tag written by What(), one written
+	// by hand with a shortened filename and one left empty.
+	cleanOK := true
+	if l, err := Get(testDir, []string{"who", "what", "where"}); err == nil {
+		for _, tag := range l {
+			testStore.Remove(testPath, tag.Raw)
+		}
+	}
+	What(testDir, "Particles")
+	fName := "1=Twain,M."
+	if err := testStore.Write(testPath, fName, []byte("Twain, Mark\n")); err != nil {
+		t.Errorf("Can't write %q, %s", fName, err)
+		t.FailNow()
+	}
+	eName := "4=Hannibal,^20Missouri"
+	if err := testStore.Write(testPath, eName, []byte{}); err != nil {
+		t.Errorf("Can't write %q, %s", eName, err)
+		t.FailNow()
+	}
+
+	values, err := GetValues(testDir, []string{"who", "what", "where"})
+	if err != nil {
+		t.Errorf("GetValues(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	if len(values) != 3 {
+		t.Errorf("expected 3 values, got %d - %+v", len(values), values)
+		t.FailNow()
+	}
+	expected := []*Value{
+		&Value{Name: "1=Twain,M.", Value: "Twain, Mark", Decoded: "Twain,M.", Mismatch: true},
+		&Value{Name: "2=Particles", Value: "Particles", Decoded: "Particles"},
+		&Value{Name: "4=Hannibal,^20Missouri", Value: "Hannibal, Missouri", Decoded: "Hannibal, Missouri"},
+	}
+	for i, val := range values {
+		if *val != *expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], val)
+			cleanOK = false
+		}
+	}
+
+	// Cleanup after test
+	if cleanOK {
+		for _, val := range expected {
+			testStore.Remove(testPath, val.Name)
 		}
 	}
 }
@@ -301,7 +287,7 @@ func TestGetTypes(t *testing.T) {
 		},
 	}
 	for key, _ := range expected {
-		os.Remove(path.Join(testDir, key))
+		testStore.Remove(testPath, key)
 		DirType(testDir, key)
 	}
 
@@ -355,18 +341,13 @@ func TestEncodeDecode(t *testing.T) {
 }
 
 func TestMain(m *testing.M) {
-	os.RemoveAll(testDir)
-	err := os.MkdirAll(testDir, 0775)
-	if err != nil {
-		log.Fatalf("Can't create dir %s, %s", testDir, err)
-	}
-	err = copyDir("docs", testDir)
-	if err != nil {
-		log.Fatalf("Can't setup %q, %q", testDir, err)
-	}
-	exitCode := m.Run()
-	if exitCode == 0 {
-		os.RemoveAll(testDir)
+	// Setup a directory with some files that are not namaste
+	RegisterStore("mem", testStore)
+	testStore.Mkdir(testPath)
+	for _, name := range []string{"README.md", "bagit.txt", "manifest-md5.txt", "0-index.html", "note.txt"} {
+		if err := testStore.Write(testPath, name, []byte(name+"\n")); err != nil {
+			log.Fatalf("Can't setup %q, %s", testDir, err)
+		}
 	}
-	os.Exit(exitCode)
+	os.Exit(m.Run())
 }
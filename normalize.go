@@ -0,0 +1,85 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// Normalization is the Unicode normalization form applied to
+	// values by Encode and the setters, "NFC" (the default), "NFD",
+	// "NFKC", "NFKD" or "none" to keep values as given. Values typed
+	// on macOS often arrive as NFD while Linux and Windows use NFC.
+	Normalization = "NFC"
+
+	normalizationForms = map[string]norm.Form{
+		"NFC":  norm.NFC,
+		"NFD":  norm.NFD,
+		"NFKC": norm.NFKC,
+		"NFKD": norm.NFKD,
+	}
+
+	// confusables maps characters that look like Latin letters to the
+	// letter, based on the Unicode confusables data (UTS #39) for the
+	// Cyrillic and Greek letters most often mistaken for Latin ones.
+	confusables = map[rune]rune{
+		// Cyrillic
+		'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H',
+		'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X', 'Ѕ': 'S',
+		'І': 'I', 'Ј': 'J', 'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p',
+		'с': 'c', 'у': 'y', 'х': 'x', 'ѕ': 's', 'і': 'i', 'ј': 'j',
+		'ԁ': 'd', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w', 'ү': 'y', 'һ': 'h',
+		// Greek
+		'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I',
+		'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T',
+		'Υ': 'Y', 'Χ': 'X', 'α': 'a', 'ι': 'i', 'ν': 'v', 'ο': 'o',
+		'ρ': 'p', 'υ': 'u', 'χ': 'x', 'η': 'n',
+	}
+)
+
+// SetNormalization sets the Unicode normalization form applied to
+// values, "NFC", "NFD", "NFKC", "NFKD" or "none"
+func SetNormalization(form string) error {
+	form = strings.ToUpper(form)
+	if _, ok := normalizationForms[form]; ok == false && form != "NONE" {
+		return fmt.Errorf("unknown normalization %q, use NFC, NFD, NFKC, NFKD or none", form)
+	}
+	Normalization = form
+	return nil
+}
+
+// NormalizeValue returns a value in the current Normalization form
+func NormalizeValue(s string) string {
+	if form, ok := normalizationForms[strings.ToUpper(Normalization)]; ok {
+		return form.String(s)
+	}
+	return s
+}
+
+// skeleton reduces a value to a form where values that differ only by
+// normalization, case or confusable characters are the same.
+func skeleton(s string) string {
+	s = norm.NFKD.String(s)
+	var sb strings.Builder
+	for _, r := range s {
+		if c, ok := confusables[r]; ok {
+			r = c
+		}
+		sb.WriteRune(r)
+	}
+	return norm.NFC.String(strings.ToLower(sb.String()))
+}
+
+// lookAlike describes how two different values that share a skeleton
+// differ
+func lookAlike(a, b string) string {
+	switch {
+	case norm.NFC.String(a) == norm.NFC.String(b):
+		return "Unicode normalization"
+	case strings.EqualFold(norm.NFC.String(a), norm.NFC.String(b)):
+		return "case"
+	}
+	return "look alike characters"
+}
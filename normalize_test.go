@@ -0,0 +1,90 @@
+package namaste
+
+import (
+	"testing"
+)
+
+func TestNormalization(t *testing.T) {
+	store, mem := newTestStore(t)
+	nfd, nfc := "Gödel, Kurt", "Gödel, Kurt"
+	defer SetNormalization("NFC")
+	if s := NormalizeValue(nfd); s != nfc {
+		t.Errorf("expected %q, got %q", nfc, s)
+	}
+	if s := Encode("who", nfd); s != Encode("who", nfc) || s != "1=Gödel,^20Kurt" {
+		t.Errorf("expected an NFC filename, got %q", s)
+	}
+	if err := SetNormalization("nfd"); err != nil {
+		t.Errorf("SetNormalization(%q) failed, %s", "nfd", err)
+	}
+	if s := NormalizeValue(nfc); s != nfd {
+		t.Errorf("expected %q, got %q", nfd, s)
+	}
+	SetNormalization("none")
+	if s := NormalizeValue(nfd); s != nfd {
+		t.Errorf("expected %q unchanged, got %q", nfd, s)
+	}
+	if err := SetNormalization("NFX"); err == nil {
+		t.Errorf("expected an error for an unknown normalization")
+	}
+	SetNormalization("NFC")
+
+	// Setters normalize the filename and contents
+	store.Mkdir("normalize-test")
+	dName := mem + "normalize-test"
+	if name, err := Who(dName, nfd); err != nil || name != "1=Gödel,^20Kurt" {
+		t.Errorf("Who() expected %q, got %q, %v", "1=Gödel,^20Kurt", name, err)
+	}
+	if src, _ := store.Read("normalize-test", "1=Gödel,^20Kurt"); string(src) != nfc+"\n" {
+		t.Errorf("expected NFC contents, got %q", src)
+	}
+}
+
+func TestSkeleton(t *testing.T) {
+	same := [][]string{
+		{"Gödel", "Gödel", "Unicode normalization"},
+		{"Twain, Mark", "twain, MARK", "case"},
+		{"Paris", "Раris", "look alike characters"},
+		{"Hamlet", "Ηamlet", "look alike characters"},
+		{"ＡＢＣ", "ABC", "look alike characters"},
+	}
+	for _, pair := range same {
+		if skeleton(pair[0]) != skeleton(pair[1]) {
+			t.Errorf("expected %q and %q to look alike", pair[0], pair[1])
+		}
+		if s := lookAlike(pair[0], pair[1]); s != pair[2] {
+			t.Errorf("expected %q and %q to differ by %s, got %s", pair[0], pair[1], pair[2], s)
+		}
+	}
+	different := [][]string{
+		{"Gödel", "Godel"},
+		{"Twain", "Twin"},
+		{"1872", "l872"},
+	}
+	for _, pair := range different {
+		if skeleton(pair[0]) == skeleton(pair[1]) {
+			t.Errorf("expected %q and %q to differ", pair[0], pair[1])
+		}
+	}
+}
+
+func TestValidateConfusables(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("confusable-test")
+	for _, name := range []string{"1=Gödel", "1=Gödel", "2=Hamlet", "2=Ηamlet", "3=1872", "4=PARIS", "4=Paris"} {
+		tag, _ := ParseTag(name)
+		store.Write("confusable-test", name, []byte(tag.Value+"\n"))
+	}
+	report, err := Validate(mem + "confusable-test")
+	if err != nil {
+		t.Errorf("Validate() failed, %s", err)
+		t.FailNow()
+	}
+	codes := map[string]int{}
+	for _, f := range report.Findings {
+		codes[f.Code]++
+	}
+	if codes["confusable-tags"] != 3 || codes["not-normalized"] != 1 || len(report.Findings) != 4 {
+		t.Errorf("unexpected findings %+v", report.Findings)
+	}
+}
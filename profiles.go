@@ -0,0 +1,74 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+)
+
+// builtinProfiles are the types registered by default
+var builtinProfiles = []*TypeProfile{
+	&TypeProfile{
+		Name:        "bagit",
+		Description: "BagIt packaging format (RFC 8493)",
+		Files:       []string{"bagit.txt", "data/", "manifest-*.txt"},
+		Validator:   validateBagIt,
+	},
+	&TypeProfile{
+		Name:        "ocfl_object",
+		Description: "Oxford Common File Layout object",
+		Files:       []string{"inventory.json", "inventory.json.*"},
+	},
+	&TypeProfile{
+		Name:        "ocfl",
+		Description: "Oxford Common File Layout storage root",
+	},
+	&TypeProfile{
+		Name:        "pairtree",
+		Description: "Pairtree file hierarchy",
+		Files:       []string{"pairtree_version*", "pairtree_root/"},
+	},
+	&TypeProfile{
+		Name:        "dflat",
+		Description: "D-flat directory hierarchy",
+		Files:       []string{"current.txt"},
+	},
+	&TypeProfile{
+		Name:        "dataset",
+		Description: "Caltech Library dataset collection",
+		Fields:      []string{"who", "what"},
+		Files:       []string{"collection.json"},
+	},
+}
+
+// validateBagIt checks the version declared in bagit.txt agrees with
+// the directory type
+func validateBagIt(dName string, t *DirectoryType) []error {
+	if t.Major == "" {
+		return nil
+	}
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return []error{err}
+	}
+	src, err := store.Read(sName, "bagit.txt")
+	if err != nil {
+		// A missing bagit.txt is reported by the profile's Files
+		return nil
+	}
+	for _, line := range strings.Split(string(src), "\n") {
+		if strings.HasPrefix(line, "BagIt-Version:") {
+			version := strings.TrimSpace(strings.TrimPrefix(line, "BagIt-Version:"))
+			if version != t.Version() {
+				return []error{fmt.Errorf("bagit.txt declares version %q", version)}
+			}
+			return nil
+		}
+	}
+	return []error{fmt.Errorf("bagit.txt is missing BagIt-Version")}
+}
+
+func init() {
+	for _, profile := range builtinProfiles {
+		RegisterType(profile)
+	}
+}
@@ -0,0 +1,243 @@
+package namaste
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TypeValidator is a hook run when checking a directory declaring a
+// type. It returns the problems found, if any.
+type TypeValidator func(dName string, t *DirectoryType) []error
+
+// TypeProfile describes what a directory type requires. Profiles are
+// registered with RegisterType or loaded from a JSON config file with
+// LoadTypeProfiles.
+type TypeProfile struct {
+	// Name is the type name, e.g. "bagit"
+	Name string `json:"name"`
+	// Versions limits the profile to matching versions using the
+	// comparisons of a TypeConstraint, e.g. ">=1.0,<2". Empty
+	// matches any version.
+	Versions string `json:"versions,omitempty"`
+	// Description is a short description of the type
+	Description string `json:"description,omitempty"`
+	// Fields are the namaste fields that must be present, e.g. "who"
+	// or "x_ark"
+	Fields []string `json:"fields,omitempty"`
+	// Files are the paths relative to the directory that must exist.
+	// A trailing "/" requires a directory and the last part of a
+	// path may be a pattern (see path.Match), e.g. "manifest-*.txt".
+	Files []string `json:"files,omitempty"`
+	// Validator is an optional hook for checks that can't be
+	// described by Fields and Files
+	Validator TypeValidator `json:"-"`
+
+	constraint *TypeConstraint
+}
+
+// TypeError is a directory failing to meet a type profile
+type TypeError struct {
+	// Type is the type as declared, e.g. "bagit_0.97"
+	Type string
+	Err  error
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Type, e.Err)
+}
+
+func (e *TypeError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	typesMutex sync.RWMutex
+	// typeProfiles holds the registered profiles by type name, later
+	// registrations take precedence
+	typeProfiles = map[string][]*TypeProfile{}
+)
+
+// RegisterType adds a profile to the type registry. A profile with
+// the same name and versions as one already registered replaces it.
+func RegisterType(profile *TypeProfile) error {
+	if profile == nil || profile.Name == "" {
+		return fmt.Errorf("type profile is missing a name")
+	}
+	c, err := ParseTypeConstraint(profile.Name + profile.Versions)
+	if err != nil {
+		return err
+	}
+	profile.constraint = c
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+	l := []*TypeProfile{}
+	for _, p := range typeProfiles[profile.Name] {
+		if p.Versions != profile.Versions {
+			l = append(l, p)
+		}
+	}
+	typeProfiles[profile.Name] = append(l, profile)
+	return nil
+}
+
+// UnregisterType removes the profiles registered for a type name
+func UnregisterType(name string) {
+	typesMutex.Lock()
+	defer typesMutex.Unlock()
+	delete(typeProfiles, name)
+}
+
+// LookupType returns the profile for a directory type or nil if the
+// type isn't registered.
+func LookupType(t *DirectoryType) *TypeProfile {
+	typesMutex.RLock()
+	defer typesMutex.RUnlock()
+	l := typeProfiles[t.Name]
+	for i := len(l) - 1; i >= 0; i-- {
+		if l[i].constraint.Match(t) {
+			return l[i]
+		}
+	}
+	return nil
+}
+
+// TypeProfiles returns the registered profiles
+func TypeProfiles() []*TypeProfile {
+	typesMutex.RLock()
+	defer typesMutex.RUnlock()
+	l := []*TypeProfile{}
+	for _, profiles := range typeProfiles {
+		l = append(l, profiles...)
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].Name == l[j].Name {
+			return l[i].Versions < l[j].Versions
+		}
+		return l[i].Name < l[j].Name
+	})
+	return l
+}
+
+// LoadTypeProfiles registers the profiles held in a JSON file, a list
+// of objects with "name", "versions", "description", "fields" and
+// "files" attributes.
+func LoadTypeProfiles(fName string) error {
+	src, err := os.ReadFile(fName)
+	if err != nil {
+		return err
+	}
+	profiles := []*TypeProfile{}
+	if err := json.Unmarshal(src, &profiles); err != nil {
+		return fmt.Errorf("%s, %s", fName, err)
+	}
+	for _, profile := range profiles {
+		if err := RegisterType(profile); err != nil {
+			return fmt.Errorf("%s, %s", fName, err)
+		}
+	}
+	return nil
+}
+
+// checkFiles returns an error for each of the required files missing
+// from a directory
+func checkFiles(store Store, dName string, files []string) []error {
+	errs := []error{}
+	for _, fName := range files {
+		wantDir := strings.HasSuffix(fName, "/")
+		fName = strings.Trim(fName, "/")
+		parent, pattern := path.Split(fName)
+		parent = path.Join(dName, parent)
+		var (
+			names []string
+			err   error
+		)
+		if wantDir {
+			if dirStore, ok := store.(DirStore); ok {
+				names, err = dirStore.Dirs(parent, false)
+			}
+		} else {
+			names, err = store.List(parent)
+		}
+		found := false
+		if err == nil {
+			for _, name := range names {
+				if ok, _ := path.Match(pattern, name); ok {
+					found = true
+					break
+				}
+			}
+		}
+		if found == false {
+			kind := "file"
+			if wantDir {
+				kind = "directory"
+			}
+			errs = append(errs, fmt.Errorf("missing %s %q", kind, fName))
+		}
+	}
+	return errs
+}
+
+// checkFields returns an error for each of the required namaste
+// fields missing from a directory
+func checkFields(dName string, fields []string) ([]error, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	tags, err := Get(dName, fields)
+	if err != nil {
+		return nil, err
+	}
+	errs := []error{}
+	for i, kind := range normalizeKinds(fields) {
+		found := false
+		for _, tag := range tags {
+			if tag.Name == kind {
+				found = true
+				break
+			}
+		}
+		if found == false {
+			errs = append(errs, fmt.Errorf("missing %q field", fields[i]))
+		}
+	}
+	return errs, nil
+}
+
+// CheckTypes checks a directory against the profiles of the types it
+// declares returning a TypeError for each problem found. Types
+// without a registered profile aren't checked.
+func CheckTypes(dName string) ([]error, error) {
+	dirTypes, err := GetDirTypes(dName)
+	if err != nil {
+		return nil, err
+	}
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return nil, err
+	}
+	results := []error{}
+	for _, t := range dirTypes {
+		profile := LookupType(t)
+		if profile == nil {
+			continue
+		}
+		errs, err := checkFields(dName, profile.Fields)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, checkFiles(store, sName, profile.Files)...)
+		if profile.Validator != nil {
+			errs = append(errs, profile.Validator(dName, t)...)
+		}
+		for _, err := range errs {
+			results = append(results, &TypeError{Type: t.String(), Err: err})
+		}
+	}
+	return results, nil
+}
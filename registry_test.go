@@ -0,0 +1,151 @@
+package namaste
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestLookupType(t *testing.T) {
+	for _, val := range []string{"bagit_0.97", "bagit", "ocfl_object_1.1", "pairtree_0.1", "dataset_1.0"} {
+		if profile := LookupType(ParseType(val)); profile == nil {
+			t.Errorf("expected a built-in profile for %q", val)
+		}
+	}
+	if profile := LookupType(ParseType("unknown_1.0")); profile != nil {
+		t.Errorf("expected no profile for unknown_1.0, got %+v", profile)
+	}
+
+	// Profiles limited by version
+	defer UnregisterType("regtest")
+	RegisterType(&TypeProfile{Name: "regtest", Files: []string{"v1.txt"}})
+	RegisterType(&TypeProfile{Name: "regtest", Versions: ">=2", Files: []string{"v2.txt"}})
+	if profile := LookupType(ParseType("regtest_1.5")); profile == nil || profile.Files[0] != "v1.txt" {
+		t.Errorf("expected the v1 profile, got %+v", profile)
+	}
+	if profile := LookupType(ParseType("regtest_2.1")); profile == nil || profile.Files[0] != "v2.txt" {
+		t.Errorf("expected the v2 profile, got %+v", profile)
+	}
+	if err := RegisterType(&TypeProfile{Name: "regtest", Versions: ">="}); err == nil {
+		t.Errorf("expected an error registering an invalid version constraint")
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("check-types-test/data")
+	dName := mem + "check-types-test"
+	DirType(dName, "bagit_0.97")
+	DirType(dName, "dataset_1.0")
+	DirType(dName, "unknown_1.0")
+	Who(dName, "Twain, Mark")
+	store.Write("check-types-test", "bagit.txt", []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n"))
+
+	errs, err := CheckTypes(dName)
+	if err != nil {
+		t.Errorf("CheckTypes(%q) failed, %s", dName, err)
+		t.FailNow()
+	}
+	expected := []string{
+		`bagit_0.97: missing file "manifest-*.txt"`,
+		`bagit_0.97: bagit.txt declares version "1.0"`,
+		`dataset_1.0: missing "what" field`,
+		`dataset_1.0: missing file "collection.json"`,
+	}
+	if len(errs) != len(expected) {
+		t.Errorf("expected %d problems, got %+v", len(expected), errs)
+		t.FailNow()
+	}
+	for i, s := range expected {
+		if errs[i].Error() != s {
+			t.Errorf("expected %q, got %q", s, errs[i])
+		}
+		var typeErr *TypeError
+		if errors.As(errs[i], &typeErr) == false {
+			t.Errorf("expected a TypeError, got %T", errs[i])
+		}
+	}
+
+	// Fix the problems
+	store.Write("check-types-test", "bagit.txt", []byte("BagIt-Version: 0.97\n"))
+	store.Write("check-types-test", "manifest-md5.txt", []byte{})
+	store.Write("check-types-test", "collection.json", []byte("{}\n"))
+	What(dName, "Roughing It")
+	errs, err = CheckTypes(dName)
+	if err != nil || len(errs) != 0 {
+		t.Errorf("expected no problems, got %+v, %v", errs, err)
+	}
+
+	// Validator hooks
+	defer UnregisterType("unknown")
+	RegisterType(&TypeProfile{
+		Name: "unknown",
+		Validator: func(dName string, dirType *DirectoryType) []error {
+			return []error{fmt.Errorf("checked %s", dirType)}
+		},
+	})
+	errs, _ = CheckTypes(dName)
+	if len(errs) != 1 || errs[0].Error() != "unknown_1.0: checked unknown_1.0" {
+		t.Errorf("expected the validator to run, got %+v", errs)
+	}
+}
+
+func TestLoadTypeProfiles(t *testing.T) {
+	store, mem := newTestStore(t)
+	fName := path.Join(t.TempDir(), "profiles.json")
+	src := []byte(`[
+	{
+		"name": "archive_item",
+		"versions": ">=1.1",
+		"description": "Archive item",
+		"fields": [ "who", "what", "x_ark" ],
+		"files": [ "objects/" ]
+	}
+]`)
+	if err := os.WriteFile(fName, src, 0664); err != nil {
+		t.Errorf("Can't write %q, %s", fName, err)
+		t.FailNow()
+	}
+	defer UnregisterType("archive_item")
+	if err := LoadTypeProfiles(fName); err != nil {
+		t.Errorf("LoadTypeProfiles(%q) failed, %s", fName, err)
+		t.FailNow()
+	}
+	profile := LookupType(ParseType("archive_item_1.2"))
+	if profile == nil || profile.Description != "Archive item" || len(profile.Fields) != 3 {
+		t.Errorf("unexpected profile %+v", profile)
+	}
+	if profile := LookupType(ParseType("archive_item_1.0")); profile != nil {
+		t.Errorf("expected no profile for version 1.0, got %+v", profile)
+	}
+	found := false
+	for _, p := range TypeProfiles() {
+		if p.Name == "archive_item" {
+			found = true
+		}
+	}
+	if found == false {
+		t.Errorf("expected archive_item in TypeProfiles()")
+	}
+
+	store.Mkdir("load-profiles-test")
+	dName := mem + "load-profiles-test"
+	DirType(dName, "archive_item_1.2")
+	Who(dName, "Twain, Mark")
+	errs, _ := CheckTypes(dName)
+	l := []string{}
+	for _, err := range errs {
+		l = append(l, err.Error())
+	}
+	if s := strings.Join(l, "; "); s != `archive_item_1.2: missing "what" field; archive_item_1.2: missing "x_ark" field; archive_item_1.2: missing directory "objects"` {
+		t.Errorf("unexpected problems %q", s)
+	}
+
+	os.WriteFile(fName, []byte(`{ "name": "broken" }`), 0664)
+	if err := LoadTypeProfiles(fName); err == nil {
+		t.Errorf("expected an error loading a malformed file")
+	}
+}
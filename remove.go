@@ -0,0 +1,86 @@
+package namaste
+
+import (
+	"fmt"
+)
+
+// FindValue returns the filenames of a field's tags holding value,
+// e.g. "who" and "Twain, Mark" returns "1=Twain,^20Mark". Values are
+// compared decoded and normalized, so the value can be given as typed.
+// The full value is read from the tag file when the filename is
+// truncated, summarized or transliterated.
+func FindValue(dName, field, value string) ([]string, error) {
+	names, err := FindAll(dName, field)
+	if err != nil {
+		return nil, err
+	}
+	value = NormalizeValue(value)
+	found := []string{}
+	for _, name := range names {
+		if holdsValue(name, "", value) {
+			found = append(found, name)
+			continue
+		}
+		if contents, err := readNamaste(dName, name); err == nil && holdsValue(name, contents, value) {
+			found = append(found, name)
+		}
+	}
+	return found, nil
+}
+
+// holdsValue returns true if the tag file name with contents holds a
+// normalized value. A truncated or summarized filename only decodes to
+// the start of its value so it is matched on its contents alone.
+func holdsValue(name, contents, value string) bool {
+	if isTruncated(name) == false && NormalizeValue(Decode(name)) == value {
+		return true
+	}
+	return contents != "" && NormalizeValue(contents) == value
+}
+
+// Remove deletes the tags of a field holding value and returns the
+// filenames removed. Values are matched as in FindValue.
+func Remove(dName, field, value string) ([]string, error) {
+	names, err := FindValue(dName, field, value)
+	if err != nil {
+		return nil, err
+	}
+	return removeNamaste(dName, names)
+}
+
+// FindAll returns the filenames of every tag of a field, e.g. all
+// "who" tags
+func FindAll(dName, field string) ([]string, error) {
+	tag, err := fieldTag(field)
+	if err != nil {
+		return nil, err
+	}
+	return getNamaste(dName, []string{tag})
+}
+
+// RemoveAll deletes every tag of a field and returns the filenames
+// removed
+func RemoveAll(dName, field string) ([]string, error) {
+	names, err := FindAll(dName, field)
+	if err != nil {
+		return nil, err
+	}
+	return removeNamaste(dName, names)
+}
+
+// removeNamaste deletes tag files returning those removed before any
+// error
+func removeNamaste(dName string, names []string) ([]string, error) {
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return nil, err
+	}
+	removed := []string{}
+	for _, name := range names {
+		if err := store.Remove(sName, name); err != nil {
+			return removed, fmt.Errorf("can't remove %q, %s", name, err)
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
@@ -0,0 +1,69 @@
+package namaste
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemove(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("remove-test")
+	dName := mem + "remove-test"
+	Who(dName, "Twain, Mark")
+	Who(dName, "Clemens, Samuel")
+	What(dName, "Huckleberry Finn")
+	Note(dName, "First line\nSecond line")
+	Extension(dName, "ark", "ark:13030/tf5p30086k")
+
+	// Values are matched decoded
+	if names, err := FindValue(dName, "who", "Twain, Mark"); err != nil || strings.Join(names, ",") != "1=Twain,^20Mark" {
+		t.Errorf("FindValue() unexpected %+v, %v", names, err)
+	}
+	removed, err := Remove(dName, "who", "Twain, Mark")
+	if err != nil || strings.Join(removed, ",") != "1=Twain,^20Mark" {
+		t.Errorf("Remove() unexpected %+v, %v", removed, err)
+	}
+	if removed, err := Remove(dName, "who", "Twain, Mark"); err != nil || len(removed) != 0 {
+		t.Errorf("expected nothing removed, got %+v, %v", removed, err)
+	}
+
+	// Summarized filenames match their full value
+	note := "note=First^20line" + hashMarker + valueHash("First line\nSecond line") + Ellipsis
+	if removed, err := Remove(dName, "note", "First line\nSecond line"); err != nil || strings.Join(removed, ",") != note {
+		t.Errorf("Remove() note unexpected %+v, %v", removed, err)
+	}
+	if removed, err := Remove(dName, "x_ark", "ark:13030/tf5p30086k"); err != nil || len(removed) != 1 {
+		t.Errorf("Remove() x_ark unexpected %+v, %v", removed, err)
+	}
+
+	// A value ending in "..." doesn't match truncated or summarized
+	// filenames starting with the same text
+	Note(dName, "Checked\nby RSD")
+	Note(dName, "Checked...")
+	What(dName, "Checked"+strings.Repeat(" and checked again", 20))
+	if removed, err := Remove(dName, "note", "Checked..."); err != nil || strings.Join(removed, ",") != "note=Checked..^2E" {
+		t.Errorf("Remove() literal ellipsis unexpected %+v, %v", removed, err)
+	}
+	if names, err := FindValue(dName, "what", "Checked..."); err != nil || len(names) != 0 {
+		t.Errorf("FindValue() expected no truncated match, got %+v, %v", names, err)
+	}
+	if names, _ := FindAll(dName, "note"); len(names) != 1 {
+		t.Errorf("expected the multi-line note kept, got %+v", names)
+	}
+	RemoveAll(dName, "note")
+	RemoveAll(dName, "what")
+	What(dName, "Huckleberry Finn")
+
+	Who(dName, "Twain, Mark")
+	removed, err = RemoveAll(dName, "who")
+	if err != nil || strings.Join(removed, ",") != "1=Clemens,^20Samuel,1=Twain,^20Mark" {
+		t.Errorf("RemoveAll() unexpected %+v, %v", removed, err)
+	}
+	tags, _ := Get(dName, nil)
+	if len(tags) != 1 || tags[0].Raw != "2=Huckleberry^20Finn" {
+		t.Errorf("expected only the what tag left, got %+v", tags)
+	}
+	if _, err := RemoveAll(dName, "author"); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,240 @@
+package namaste
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RepairSource chooses which half of a tag Repair trusts when the
+// filename and contents disagree
+type RepairSource int
+
+const (
+	// FromContents rebuilds filenames from the tag files' contents,
+	// the contents hold the full value so this is the default
+	FromContents RepairSource = iota
+	// FromFilenames rebuilds contents from the filenames. Truncated
+	// filenames still take their value from the contents.
+	FromFilenames
+)
+
+// ParseRepairSource returns the RepairSource for "contents" or
+// "filenames"
+func ParseRepairSource(s string) (RepairSource, error) {
+	switch strings.ToLower(s) {
+	case "contents", "content", "":
+		return FromContents, nil
+	case "filenames", "filename", "names":
+		return FromFilenames, nil
+	}
+	return FromContents, fmt.Errorf("%q is not a repair source, use contents or filenames", s)
+}
+
+// RepairAction is a single fix planned by PlanRepair
+type RepairAction struct {
+	// Op is "rename", "write" or "remove"
+	Op string `json:"op"`
+	// Filename is the existing tag file
+	Filename string `json:"filename"`
+	// Target is the filename written by a rename or write
+	Target string `json:"target,omitempty"`
+	// Previous holds the existing contents
+	Previous string `json:"previous"`
+	// Contents are the contents written to Target
+	Contents string `json:"contents,omitempty"`
+	// Reason describes why the action is needed
+	Reason string `json:"reason"`
+}
+
+// RepairPlan is the set of fixes needed for a directory's tags
+type RepairPlan struct {
+	Path    string          `json:"path"`
+	Actions []*RepairAction `json:"actions"`
+	// Skipped lists tags that can't be repaired and why
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// PlanRepair works out the fixes needed to make each tag's filename
+// and contents agree. Badly encoded filenames are renamed, missing or
+// stale contents are rewritten and duplicate tags that differ only in
+// encoding are removed. Nothing is changed until the plan is applied.
+func PlanRepair(dName string, source RepairSource) (*RepairPlan, error) {
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDir(store, sName); err != nil {
+		return nil, err
+	}
+	names, err := getNamaste(dName, defaultKinds)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	plan := &RepairPlan{Path: dName, Actions: []*RepairAction{}}
+	actions := []*RepairAction{}
+	values := map[*RepairAction]string{}
+	for _, name := range names {
+		tag, err := ParseTag(name)
+		if err != nil {
+			continue
+		}
+		src, err := store.Read(sName, name)
+		if err != nil {
+			return nil, err
+		}
+		contents := trimNewline(string(src))
+		val := tag.Value
+		switch {
+		case tag.Truncated && contents == "":
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: truncated and the full value is missing from the contents", name))
+			continue
+		case tag.Truncated, source == FromContents && contents != "":
+			val = contents
+		}
+		val = NormalizeValue(val)
+		action := &RepairAction{
+			Filename: name,
+			Target:   Encode(tag.Name, val),
+			Previous: string(src),
+			Contents: terminateValue(val),
+		}
+		if action.Target != name && isEncoded(tag.Name, val, name) {
+			// Written by a stricter encoding profile
+			action.Target = name
+		}
+		switch {
+		case action.Target != name:
+			action.Op = "rename"
+			action.Reason = "filename is not correctly encoded"
+			if tag.Truncated == false && val != tag.Value {
+				action.Reason = "filename disagrees with the contents"
+			}
+		case contents == "":
+			action.Op = "write"
+			action.Reason = "contents are missing"
+		case contents != val:
+			action.Op = "write"
+			action.Reason = "contents disagree with the filename"
+		}
+		actions = append(actions, action)
+		values[action] = val
+	}
+
+	// Tags that keep their filename claim it before any renames so
+	// duplicates differing only in encoding are the ones removed
+	targets := map[string]string{}
+	for _, action := range actions {
+		if action.Target == action.Filename {
+			targets[action.Target] = values[action]
+			if action.Op != "" {
+				plan.Actions = append(plan.Actions, action)
+			}
+		}
+	}
+	for _, action := range actions {
+		if action.Op != "rename" {
+			continue
+		}
+		val := values[action]
+		if prev, ok := targets[action.Target]; ok {
+			if prev != val {
+				plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: value conflicts with %s", action.Filename, action.Target))
+				continue
+			}
+			action.Op, action.Target, action.Contents = "remove", "", ""
+			action.Reason = "duplicate of another tag"
+		} else {
+			targets[action.Target] = val
+		}
+		plan.Actions = append(plan.Actions, action)
+	}
+	return plan, nil
+}
+
+// Diff returns the plan as a unified diff style listing of the
+// filenames and contents changed
+func (plan *RepairPlan) Diff() string {
+	l := []string{}
+	for _, action := range plan.Actions {
+		target := action.Target
+		if target == "" {
+			target = "/dev/null"
+		}
+		l = append(l, fmt.Sprintf("--- %s", action.Filename), fmt.Sprintf("+++ %s", target), fmt.Sprintf("@@ %s, %s @@", action.Op, action.Reason))
+		previous, contents := trimNewline(action.Previous), trimNewline(action.Contents)
+		if previous != "" && (previous != contents || action.Op == "remove") {
+			l = append(l, diffLines("-", previous)...)
+		}
+		if contents != "" && previous != contents {
+			l = append(l, diffLines("+", contents)...)
+		}
+	}
+	for _, s := range plan.Skipped {
+		l = append(l, "# skipped "+s)
+	}
+	if len(l) == 0 {
+		return ""
+	}
+	return strings.Join(l, "\n") + "\n"
+}
+
+// diffLines prefixes each line of a multi-line value for Diff
+func diffLines(prefix, s string) []string {
+	l := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range l {
+		l[i] = prefix + line
+	}
+	return l
+}
+
+// Apply carries out the plan. New filenames and contents are written
+// first and only then are old filenames removed, so a value is never
+// lost, even if the process is killed part way through. If a write or
+// remove fails the files already changed are restored and the
+// directory is left as it was.
+func (plan *RepairPlan) Apply() error {
+	store, sName, err := OpenStore(plan.Path)
+	if err != nil {
+		return err
+	}
+	for _, action := range plan.Actions {
+		if err := checkNameLength(action.Target); err != nil {
+			return err
+		}
+	}
+	changes := &undoLog{store: store, dName: sName}
+	for _, action := range plan.Actions {
+		if action.Target == "" {
+			continue
+		}
+		if err := changes.write(action.Target, []byte(action.Contents)); err != nil {
+			changes.rollback()
+			return fmt.Errorf("repair of %q failed, %s", plan.Path, err)
+		}
+	}
+	written := map[string]bool{}
+	for _, action := range plan.Actions {
+		written[action.Target] = true
+	}
+	for _, action := range plan.Actions {
+		if (action.Op == "remove" || action.Op == "rename") && written[action.Filename] == false {
+			if err := changes.remove(action.Filename); err != nil {
+				changes.rollback()
+				return fmt.Errorf("repair of %q failed, can't remove %q, %s", plan.Path, action.Filename, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Repair plans and applies the fixes for a directory's tags. See
+// PlanRepair.
+func Repair(dName string, source RepairSource) (*RepairPlan, error) {
+	plan, err := PlanRepair(dName, source)
+	if err != nil {
+		return nil, err
+	}
+	return plan, plan.Apply()
+}
@@ -0,0 +1,165 @@
+package namaste
+
+import (
+	"strings"
+	"testing"
+)
+
+// setupRepair creates a directory in a memory store holding files
+func setupRepair(store *MemoryStore, dName string, files map[string]string) {
+	store.Mkdir(dName)
+	for name, src := range files {
+		store.Write(dName, name, []byte(src))
+	}
+}
+
+// listRepair returns the files in a directory of a memory store
+func listRepair(store *MemoryStore, dName string) map[string]string {
+	names, _ := store.List(dName)
+	m := map[string]string{}
+	for _, name := range names {
+		src, _ := store.Read(dName, name)
+		m[name] = string(src)
+	}
+	return m
+}
+
+func TestRepair(t *testing.T) {
+	store, mem := newTestStore(t)
+	files := map[string]string{
+		"0=bagit_0.97":    "",
+		"1=Twain, Mark":   "Twain, Mark\n",
+		"1=Twain,^20Mark": "Twain, Mark\n",
+		"2=Roughing^20It": "Roughing It, Volume 1\n",
+		"3=1872":          "1872\r\n",
+		"4=Hartford...":   "",
+		"x_ark=ark:13030": "ark:13030\n",
+		"README.md":       "Not a tag\n",
+	}
+
+	// Rebuild filenames from contents
+	setupRepair(store, "repair-test/contents", files)
+	dName := mem + "repair-test/contents"
+	plan, err := PlanRepair(dName, FromContents)
+	if err != nil {
+		t.Errorf("PlanRepair(%q) failed, %s", dName, err)
+		t.FailNow()
+	}
+	expected := []string{
+		"write 0=bagit_0.97 0=bagit_0.97",
+		"remove 1=Twain, Mark ",
+		"rename 2=Roughing^20It 2=Roughing^20It,^20Volume^201",
+		"rename x_ark=ark:13030 x_ark=ark^3A13030",
+	}
+	l := []string{}
+	for _, action := range plan.Actions {
+		l = append(l, action.Op+" "+action.Filename+" "+action.Target)
+	}
+	if strings.Join(l, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected actions\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(l, "\n"))
+	}
+	if len(plan.Skipped) != 1 || strings.HasPrefix(plan.Skipped[0], "4=Hartford...") == false {
+		t.Errorf("expected 4=Hartford... to be skipped, got %+v", plan.Skipped)
+	}
+	diff := plan.Diff()
+	for _, s := range []string{"--- 1=Twain, Mark\n+++ /dev/null\n", "+++ 2=Roughing^20It,^20Volume^201\n", "+bagit_0.97\n"} {
+		if strings.Contains(diff, s) == false {
+			t.Errorf("expected diff to contain %q, got\n%s", s, diff)
+		}
+	}
+
+	// A dry run changes nothing
+	if m := listRepair(store, "repair-test/contents"); len(m) != len(files) {
+		t.Errorf("expected %d files before applying, got %+v", len(files), m)
+	}
+	if err := plan.Apply(); err != nil {
+		t.Errorf("Apply() failed, %s", err)
+	}
+	expectedFiles := map[string]string{
+		"0=bagit_0.97":                  "bagit_0.97\n",
+		"1=Twain,^20Mark":               "Twain, Mark\n",
+		"2=Roughing^20It,^20Volume^201": "Roughing It, Volume 1\n",
+		"3=1872":                        "1872\r\n",
+		"4=Hartford...":                 "",
+		"x_ark=ark^3A13030":             "ark:13030\n",
+		"README.md":                     "Not a tag\n",
+	}
+	m := listRepair(store, "repair-test/contents")
+	if len(m) != len(expectedFiles) {
+		t.Errorf("expected %d files, got %+v", len(expectedFiles), m)
+	}
+	for name, src := range expectedFiles {
+		if m[name] != src {
+			t.Errorf("expected %q to hold %q, got %q", name, src, m[name])
+		}
+	}
+
+	// A repaired directory needs nothing more
+	plan, _ = PlanRepair(dName, FromContents)
+	if len(plan.Actions) != 0 || plan.Diff() != "# skipped 4=Hartford...: truncated and the full value is missing from the contents\n" {
+		t.Errorf("expected no more actions, got %+v\n%s", plan.Actions, plan.Diff())
+	}
+
+	// Rebuild contents from filenames
+	setupRepair(store, "repair-test/filenames", files)
+	dName = mem + "repair-test/filenames"
+	if _, err := Repair(dName, FromFilenames); err != nil {
+		t.Errorf("Repair(%q) failed, %s", dName, err)
+	}
+	m = listRepair(store, "repair-test/filenames")
+	if m["2=Roughing^20It"] != "Roughing It\n" || m["1=Twain,^20Mark"] != "Twain, Mark\n" {
+		t.Errorf("unexpected files %+v", m)
+	}
+	if _, ok := m["1=Twain, Mark"]; ok {
+		t.Errorf("expected the duplicate to be removed, got %+v", m)
+	}
+}
+
+func TestRepairSwap(t *testing.T) {
+	store, mem := newTestStore(t)
+	setupRepair(store, "repair-test/swap", map[string]string{
+		"2=Foo": "Bar\n",
+		"2=Bar": "Foo\n",
+	})
+	if _, err := Repair(mem+"repair-test/swap", FromContents); err != nil {
+		t.Errorf("Repair() failed, %s", err)
+	}
+	m := listRepair(store, "repair-test/swap")
+	if len(m) != 2 || m["2=Foo"] != "Foo\n" || m["2=Bar"] != "Bar\n" {
+		t.Errorf("unexpected files %+v", m)
+	}
+	for _, s := range []string{"contents", "filenames", ""} {
+		if _, err := ParseRepairSource(s); err != nil {
+			t.Errorf("ParseRepairSource(%q) failed, %s", s, err)
+		}
+	}
+	if _, err := ParseRepairSource("both"); err == nil {
+		t.Errorf("expected an error parsing %q", "both")
+	}
+}
+
+func TestRepairRollback(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore()}
+	RegisterStore("repairtest", store)
+	defer RegisterStore("repairtest", nil)
+	files := map[string]string{
+		"1=Twain,M.": "Twain, Mark\n",
+		"2=Huck":     "Huckleberry Finn\n",
+	}
+	setupRepair(store.MemoryStore, "item1", files)
+
+	// A failed remove restores the files already written and removed
+	store.fail = "2=Huck"
+	if _, err := Repair("repairtest://item1", FromContents); err == nil {
+		t.Errorf("expected the repair to fail")
+	}
+	m := listRepair(store.MemoryStore, "item1")
+	if len(m) != len(files) {
+		t.Errorf("expected %+v after rollback, got %+v", files, m)
+	}
+	for name, src := range files {
+		if m[name] != src {
+			t.Errorf("expected %q to hold %q, got %q", name, src, m[name])
+		}
+	}
+}
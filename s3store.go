@@ -0,0 +1,346 @@
+package namaste
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Store keeps namaste tags in an S3 compatible object store (e.g.
+// AWS S3 or MinIO). Directory names take the form BUCKET/PREFIX and
+// tags are small objects named PREFIX/TAG, e.g. "0=bagit_0.1".
+// Requests use path style addressing and are signed with AWS
+// Signature Version 4 when credentials are available.
+type S3Store struct {
+	// Endpoint is the base URL of the service, e.g.
+	// https://s3.us-west-2.amazonaws.com or http://localhost:9000
+	Endpoint string
+	// Region used to sign requests, defaults to us-east-1
+	Region string
+	// Credentials, requests are not signed if AccessKeyID is empty
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Client is the HTTP client used, defaults to http.DefaultClient
+	Client *http.Client
+}
+
+// NewS3Store returns an S3Store configured from the environment
+// variables used by the AWS command line tools. AWS_ENDPOINT_URL_S3
+// or AWS_ENDPOINT_URL sets the endpoint for S3 compatible services.
+func NewS3Store() *S3Store {
+	store := &S3Store{
+		Region:          os.Getenv("AWS_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL_S3"),
+	}
+	if store.Region == "" {
+		store.Region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if store.Endpoint == "" {
+		store.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	return store
+}
+
+func init() {
+	RegisterStore("s3", NewS3Store())
+}
+
+// s3Error is the error document returned by S3
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// s3ListResult is the result of a ListObjectsV2 request
+type s3ListResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (store *S3Store) region() string {
+	if store.Region == "" {
+		return "us-east-1"
+	}
+	return store.Region
+}
+
+func (store *S3Store) endpoint() string {
+	if store.Endpoint == "" {
+		return fmt.Sprintf("https://s3.%s.amazonaws.com", store.region())
+	}
+	return strings.TrimSuffix(store.Endpoint, "/")
+}
+
+// escapePath encodes an object path per the S3 rules, everything but
+// unreserved characters and "/" is percent encoded.
+func escapePath(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// escapeQuery encodes query parameters per the S3 rules
+func escapeQuery(query url.Values) string {
+	keys := []string{}
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	l := []string{}
+	for _, k := range keys {
+		for _, v := range query[k] {
+			l = append(l, strings.ReplaceAll(escapePath(k), "/", "%2F")+"="+strings.ReplaceAll(escapePath(v), "/", "%2F"))
+		}
+	}
+	return strings.Join(l, "&")
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (store *S3Store) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	day := amzDate[0:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if store.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", store.SessionToken)
+	}
+	if store.AccessKeyID == "" {
+		return
+	}
+	headers := map[string]string{
+		"host": req.URL.Host,
+	}
+	for k, v := range req.Header {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-amz-") || k == "content-type" {
+			headers[k] = strings.TrimSpace(strings.Join(v, ","))
+		}
+	}
+	names := []string{}
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	canonicalHeaders := ""
+	for _, k := range names {
+		canonicalHeaders += k + ":" + headers[k] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		escapeQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", day, store.region())
+	h := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(h[:]),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+store.SecretAccessKey), day)
+	key = hmacSHA256(key, store.region())
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// do sends a signed request for bucket and key returning the response
+// body. A missing object is reported as fs.ErrNotExist.
+func (store *S3Store) do(method, bucket, key string, query url.Values, src []byte) ([]byte, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("missing S3 bucket name")
+	}
+	u := store.endpoint() + "/" + escapePath(bucket)
+	if key != "" {
+		u += "/" + escapePath(key)
+	}
+	if len(query) > 0 {
+		u += "?" + escapeQuery(query)
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.Sum256(src)
+	store.sign(req, hex.EncodeToString(h[:]), time.Now())
+	client := store.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode == http.StatusNotFound {
+		return nil, &fs.PathError{Op: strings.ToLower(method), Path: "s3://" + bucket + "/" + key, Err: fs.ErrNotExist}
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		e := new(s3Error)
+		if xml.Unmarshal(body, e) == nil && e.Code != "" {
+			return nil, fmt.Errorf("s3://%s/%s, %s: %s", bucket, key, e.Code, e.Message)
+		}
+		return nil, fmt.Errorf("s3://%s/%s, %s", bucket, key, res.Status)
+	}
+	return body, nil
+}
+
+// Stat confirms the bucket exists, a prefix is always a directory
+func (store *S3Store) Stat(dName string) (fs.FileInfo, error) {
+	bucket, prefix := splitBucket(dName)
+	if _, err := store.do(http.MethodHead, bucket, "", nil, nil); err != nil {
+		return nil, err
+	}
+	name := bucket
+	if prefix != "" {
+		name = prefix[strings.LastIndex(prefix, "/")+1:]
+	}
+	return &dirInfo{name: name}, nil
+}
+
+// list calls fnName for each object and fnDir for each common prefix
+// directly under a prefix, a page of results at a time
+func (store *S3Store) list(dName string, fnName func(string) error, fnDir func(string) error) error {
+	bucket, prefix := splitBucket(dName)
+	if prefix != "" {
+		prefix += "/"
+	}
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("delimiter", "/")
+	query.Set("prefix", prefix)
+	for {
+		body, err := store.do(http.MethodGet, bucket, "", query, nil)
+		if err != nil {
+			return err
+		}
+		result := new(s3ListResult)
+		if err := xml.Unmarshal(body, result); err != nil {
+			return err
+		}
+		for _, obj := range result.Contents {
+			if name := strings.TrimPrefix(obj.Key, prefix); name != "" {
+				if err := fnName(name); err != nil {
+					return err
+				}
+			}
+		}
+		for _, obj := range result.CommonPrefixes {
+			if name := strings.Trim(strings.TrimPrefix(obj.Prefix, prefix), "/"); name != "" {
+				if err := fnDir(name); err != nil {
+					return err
+				}
+			}
+		}
+		if result.IsTruncated == false || result.NextContinuationToken == "" {
+			break
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+	return nil
+}
+
+// List returns the names of the objects directly under a prefix
+func (store *S3Store) List(dName string) ([]string, error) {
+	names := []string{}
+	err := store.ListEach(dName, func(name string) error {
+		names = append(names, name)
+		return nil
+	})
+	return names, err
+}
+
+// ListEach calls fn with the name of each object directly under a
+// prefix, a page of results at a time
+func (store *S3Store) ListEach(dName string, fn func(name string) error) error {
+	return store.list(dName, fn, func(string) error { return nil })
+}
+
+// Dirs returns the names of the prefixes directly under a prefix
+func (store *S3Store) Dirs(dName string, followLinks bool) ([]string, error) {
+	dirs := []string{}
+	err := store.list(dName, func(string) error { return nil }, func(name string) error {
+		dirs = append(dirs, name)
+		return nil
+	})
+	return dirs, err
+}
+
+// ListDir returns the names of the objects and the prefixes directly
+// under a prefix from one listing
+func (store *S3Store) ListDir(dName string, followLinks bool) ([]string, []string, error) {
+	names, dirs := []string{}, []string{}
+	err := store.list(dName, func(name string) error {
+		names = append(names, name)
+		return nil
+	}, func(name string) error {
+		dirs = append(dirs, name)
+		return nil
+	})
+	return names, dirs, err
+}
+
+// Read returns the contents of a tag object
+func (store *S3Store) Read(dName, name string) ([]byte, error) {
+	bucket, prefix := splitBucket(dName)
+	return store.do(http.MethodGet, bucket, objectKey(prefix, name), nil, nil)
+}
+
+// Write creates or replaces a tag object
+func (store *S3Store) Write(dName, name string, src []byte) error {
+	bucket, prefix := splitBucket(dName)
+	_, err := store.do(http.MethodPut, bucket, objectKey(prefix, name), nil, src)
+	return err
+}
+
+// Remove deletes a tag object
+func (store *S3Store) Remove(dName, name string) error {
+	bucket, prefix := splitBucket(dName)
+	_, err := store.do(http.MethodDelete, bucket, objectKey(prefix, name), nil, nil)
+	return err
+}
@@ -0,0 +1,300 @@
+package namaste
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultWorkers is the number of directories Scan reads at once
+	// when ScanOptions.Workers isn't set. Scanning is I/O bound so it
+	// is not tied to the number of CPUs.
+	DefaultWorkers = 8
+)
+
+// ScanOptions controls a Scan. The embedded WalkOptions work the same
+// as for Walk.
+type ScanOptions struct {
+	WalkOptions
+	// Workers is the number of directories read at once
+	Workers int `json:"workers,omitempty"`
+	// Ordered returns results in the same order as Walk, otherwise
+	// results are returned as soon as they are ready.
+	Ordered bool `json:"ordered,omitempty"`
+}
+
+// ScanResult is a directory with namaste tags found by Scan. If the
+// directory couldn't be read Err is set.
+type ScanResult struct {
+	Path string `json:"path"`
+	Tags []Tag  `json:"tags,omitempty"`
+	Err  error  `json:"-"`
+}
+
+// scanNode is a directory in the tree being scanned
+type scanNode struct {
+	dName    string
+	rel      string
+	depth    int
+	tags     []Tag
+	err      error
+	children []*scanNode
+	done     chan struct{}
+}
+
+// scanQueue is an unbounded queue of directories waiting to be read.
+// It is closed once every directory queued has been read or when the
+// scan is canceled.
+type scanQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	nodes   []*scanNode
+	pending int
+	closed  bool
+}
+
+func newScanQueue() *scanQueue {
+	q := new(scanQueue)
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds directories to the queue
+func (q *scanQueue) push(nodes ...*scanNode) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.nodes = append(q.nodes, nodes...)
+	q.pending += len(nodes)
+	q.cond.Broadcast()
+}
+
+// pop waits for the next directory, returning false once the queue
+// is closed.
+func (q *scanQueue) pop() (*scanNode, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.nodes) == 0 && q.closed == false {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, false
+	}
+	node := q.nodes[0]
+	q.nodes = q.nodes[1:]
+	return node, true
+}
+
+// done marks a directory popped from the queue as read
+func (q *scanQueue) done() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending--
+	if q.pending == 0 {
+		q.closed = true
+		q.cond.Broadcast()
+	}
+}
+
+// close stops the queue
+func (q *scanQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// scanner holds the state of a Scan
+type scanner struct {
+	store    Store
+	dirStore DirStore
+	scheme   string
+	opts     *ScanOptions
+	queue    *scanQueue
+	results  chan *ScanResult
+
+	mu      sync.Mutex
+	visited map[string]bool
+}
+
+// seen returns true if a directory has already been scanned
+func (s *scanner) seen(dName string) bool {
+	if s.opts.FollowSymlinks == false {
+		return false
+	}
+	key := dName
+	if _, ok := s.store.(*LocalStore); ok {
+		if p, err := filepath.EvalSymlinks(dName); err == nil {
+			key = p
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.visited[key] {
+		return true
+	}
+	s.visited[key] = true
+	return false
+}
+
+// read lists a directory finding its tags and subdirectories
+func (s *scanner) read(node *scanNode) {
+	defer close(node.done)
+	if s.seen(node.dName) {
+		return
+	}
+	names, dirs, err := s.dirStore.ListDir(node.dName, s.opts.FollowSymlinks)
+	if err != nil {
+		node.err = err
+		return
+	}
+	if node.tags, err = readValues(s.scheme+node.dName, parseTags(names)); err != nil {
+		node.err = err
+		return
+	}
+	if s.opts.MaxDepth > 0 && node.depth >= s.opts.MaxDepth {
+		return
+	}
+	for _, name := range dirs {
+		rel := path.Join(node.rel, name)
+		if s.opts.ignored(rel) {
+			continue
+		}
+		node.children = append(node.children, &scanNode{
+			dName: path.Join(node.dName, name),
+			rel:   rel,
+			depth: node.depth + 1,
+			done:  make(chan struct{}),
+		})
+	}
+}
+
+// send returns a node's result if it has tags or an error, it returns
+// false if the scan was canceled.
+func (s *scanner) send(ctx context.Context, node *scanNode) bool {
+	if len(node.tags) == 0 && node.err == nil {
+		return true
+	}
+	result := &ScanResult{
+		Path: s.scheme + node.dName,
+		Tags: node.tags,
+		Err:  node.err,
+	}
+	select {
+	case s.results <- result:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// worker reads directories from the queue until it is closed
+func (s *scanner) worker(ctx context.Context) {
+	for {
+		node, ok := s.queue.pop()
+		if ok == false {
+			return
+		}
+		if ctx.Err() == nil {
+			s.read(node)
+			s.queue.push(node.children...)
+			if s.opts.Ordered == false && s.send(ctx, node) == false {
+				s.queue.close()
+			}
+		}
+		s.queue.done()
+	}
+}
+
+// emit sends results in Walk order, waiting for each directory to
+// be read.
+func (s *scanner) emit(ctx context.Context, node *scanNode) bool {
+	select {
+	case <-node.done:
+	case <-ctx.Done():
+		return false
+	}
+	if s.send(ctx, node) == false {
+		return false
+	}
+	for _, child := range node.children {
+		if s.emit(ctx, child) == false {
+			return false
+		}
+	}
+	return true
+}
+
+// Scan reads the tree starting at root using several workers at once
+// and returns a channel of the directories with namaste tags. The
+// channel is closed when the scan is complete or ctx is canceled.
+// Directories that can't be read are returned with Err set and the
+// scan continues. root may name any store that works with Walk.
+func Scan(ctx context.Context, root string, opts *ScanOptions) (<-chan *ScanResult, error) {
+	if opts == nil {
+		opts = new(ScanOptions)
+	}
+	store, dName, err := OpenStore(root)
+	if err != nil {
+		return nil, err
+	}
+	dirStore, ok := store.(DirStore)
+	if ok == false {
+		return nil, fmt.Errorf("can't scan %q, storage has no subdirectories", root)
+	}
+	if err := checkDir(store, dName); err != nil {
+		return nil, err
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	s := &scanner{
+		store:    store,
+		dirStore: dirStore,
+		opts:     opts,
+		queue:    newScanQueue(),
+		results:  make(chan *ScanResult, workers),
+		visited:  map[string]bool{},
+	}
+	if i := strings.Index(root, "://"); i > 0 {
+		s.scheme = root[0 : i+3]
+	}
+	rootNode := &scanNode{
+		dName: dName,
+		rel:   ".",
+		done:  make(chan struct{}),
+	}
+	s.queue.push(rootNode)
+
+	// Stop the workers if the scan is canceled
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-ctx.Done()
+		s.queue.close()
+	}()
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.worker(ctx)
+		}()
+	}
+	go func() {
+		if opts.Ordered {
+			s.emit(ctx, rootNode)
+		}
+		wg.Wait()
+		cancel()
+		close(s.results)
+	}()
+	return s.results, nil
+}
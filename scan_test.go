@@ -0,0 +1,105 @@
+package namaste
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	// Setup a collection with nested items
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		for j := 0; j < 5; j++ {
+			dName := path.Join(root, fmt.Sprintf("box%02d", i), fmt.Sprintf("item%02d", j))
+			if err := os.MkdirAll(path.Join(dName, "data"), 0775); err != nil {
+				t.Errorf("Can't create %q, %s", dName, err)
+				t.FailNow()
+			}
+			DirType(dName, "bagit_0.97")
+			What(dName, fmt.Sprintf("Box %d, Item %d", i, j))
+		}
+		if i%2 == 0 {
+			What(path.Join(root, fmt.Sprintf("box%02d", i)), fmt.Sprintf("Box %d", i))
+		}
+	}
+	found, err := Find(root, nil)
+	if err != nil {
+		t.Errorf("Find(%q) failed, %s", root, err)
+		t.FailNow()
+	}
+	if len(found) != 110 {
+		t.Errorf("expected 110 directories, got %d", len(found))
+	}
+
+	// Ordered results match Walk
+	results, err := Scan(context.Background(), root, &ScanOptions{Workers: 4, Ordered: true})
+	if err != nil {
+		t.Errorf("Scan(%q) failed, %s", root, err)
+		t.FailNow()
+	}
+	i := 0
+	for result := range results {
+		if result.Err != nil {
+			t.Errorf("unexpected error %s", result.Err)
+		}
+		if i < len(found) && (result.Path != found[i].Path || len(result.Tags) != len(found[i].Tags)) {
+			t.Errorf("expected %q, got %q", found[i].Path, result.Path)
+		}
+		i++
+	}
+	if i != len(found) {
+		t.Errorf("expected %d ordered results, got %d", len(found), i)
+	}
+
+	// Unordered results hold the same directories
+	results, err = Scan(context.Background(), root, &ScanOptions{Workers: 8})
+	if err != nil {
+		t.Errorf("Scan(%q) failed, %s", root, err)
+		t.FailNow()
+	}
+	paths := []string{}
+	for result := range results {
+		paths = append(paths, result.Path)
+	}
+	sort.Strings(paths)
+	if len(paths) != len(found) {
+		t.Errorf("expected %d results, got %d", len(found), len(paths))
+	} else {
+		for i, dir := range found {
+			if paths[i] != dir.Path {
+				t.Errorf("expected %q, got %q", dir.Path, paths[i])
+			}
+		}
+	}
+
+	// Options are applied as in Walk
+	results, _ = Scan(context.Background(), root, &ScanOptions{WalkOptions: WalkOptions{MaxDepth: 1, Ignore: []string{"box1*"}}})
+	count := 0
+	for range results {
+		count++
+	}
+	if count != 5 {
+		t.Errorf("expected 5 results, got %d", count)
+	}
+
+	// Canceling stops the scan and closes the channel
+	ctx, cancel := context.WithCancel(context.Background())
+	results, _ = Scan(ctx, root, &ScanOptions{Workers: 2, Ordered: true})
+	<-results
+	cancel()
+	count = 0
+	for range results {
+		count++
+	}
+	if count >= len(found)-1 {
+		t.Errorf("expected scan to stop early, got %d more results", count)
+	}
+
+	if _, err := Scan(context.Background(), path.Join(root, "missing"), nil); err == nil {
+		t.Errorf("expected an error scanning a missing directory")
+	}
+}
@@ -0,0 +1,188 @@
+package namaste
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SetMode chooses what a setter does with existing tags of the same
+// field. The Namaste Spec allows a field to repeat, e.g. several
+// authors each with their own "1=" tag.
+type SetMode int
+
+const (
+	// AddValue writes another tag alongside any existing ones, it is
+	// the default
+	AddValue SetMode = iota
+	// ReplaceValue removes the existing tags of the field once the
+	// new one is written
+	ReplaceValue
+	// SetIfAbsent writes the tag only if the field has no tags yet
+	SetIfAbsent
+)
+
+var setModeNames = []string{"add", "replace", "set-if-absent"}
+
+// String returns the mode's name, e.g. "replace"
+func (mode SetMode) String() string {
+	if mode >= 0 && int(mode) < len(setModeNames) {
+		return setModeNames[mode]
+	}
+	return fmt.Sprintf("SetMode(%d)", int(mode))
+}
+
+// ParseSetMode returns the SetMode for "add", "replace" or
+// "set-if-absent"
+func ParseSetMode(s string) (SetMode, error) {
+	switch strings.ToLower(s) {
+	case "add", "":
+		return AddValue, nil
+	case "replace":
+		return ReplaceValue, nil
+	case "set-if-absent", "if-absent":
+		return SetIfAbsent, nil
+	}
+	return AddValue, fmt.Errorf("%q is not a set mode, use add, replace or set-if-absent", s)
+}
+
+// SetResult reports the files changed by SetValue
+type SetResult struct {
+	// Filename is the tag written, empty if nothing was written
+	Filename string `json:"filename"`
+	// Created lists the tag files that didn't exist before
+	Created []string `json:"created"`
+	// Removed lists the tag files removed
+	Removed []string `json:"removed"`
+}
+
+// fieldTag returns the tag label for a field name, e.g. "1" for
+// "who", "note" or an extension name such as "x_ark"
+func fieldTag(field string) (string, error) {
+	if s, ok := normalizeFieldName[strings.ToLower(field)]; ok {
+		return s, nil
+	}
+	if _, ok := fieldLabels[field]; ok || field == "note" {
+		return field, nil
+	}
+	if strings.HasPrefix(field, ExtensionPrefix) {
+		return ExtensionName(field)
+	}
+	return "", fmt.Errorf("%q is not a namaste field", field)
+}
+
+// SetValue sets a field of a directory, e.g. "who", "2", "note" or
+// "x_ark", using mode to decide what happens to the field's existing
+// tags. The new tag is written before any old ones are removed so
+// the field is never left without a value.
+func SetValue(dName, field, value string, mode SetMode) (*SetResult, error) {
+	tag, err := fieldTag(field)
+	if err != nil {
+		return nil, err
+	}
+	return setTag(dName, tag, value, mode)
+}
+
+// setTag writes a tag for value using mode. In AddValue mode a tag
+// file of the same name holding a different value isn't overwritten.
+func setTag(dName, tag, value string, mode SetMode) (*SetResult, error) {
+	result := &SetResult{Created: []string{}, Removed: []string{}}
+	existing, err := getNamaste(dName, []string{tag})
+	if err != nil {
+		return nil, err
+	}
+	if mode == SetIfAbsent && len(existing) > 0 {
+		return result, nil
+	}
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return nil, err
+	}
+	value = NormalizeValue(value)
+	name := Encode(tag, value)
+	if err := checkNameLength(name); err != nil {
+		return nil, err
+	}
+	created := true
+	for _, old := range existing {
+		if old == name {
+			created = false
+		}
+	}
+	if created == false && mode == AddValue {
+		src, err := store.Read(sName, name)
+		if err != nil {
+			return nil, err
+		}
+		if contents := trimNewline(string(src)); contents != "" && contents != value {
+			return nil, fmt.Errorf("%q already holds a different value, %q", name, contents)
+		}
+	}
+	if err := store.Write(sName, name, []byte(terminateValue(value))); err != nil {
+		return nil, err
+	}
+	result.Filename = name
+	if created {
+		result.Created = append(result.Created, name)
+	}
+	if mode != ReplaceValue {
+		return result, nil
+	}
+	for _, old := range existing {
+		if old == name {
+			continue
+		}
+		if err := store.Remove(sName, old); err != nil {
+			return result, fmt.Errorf("can't remove %q, %s", old, err)
+		}
+		result.Removed = append(result.Removed, old)
+	}
+	return result, nil
+}
+
+// Set adds values to several fields of a directory at once, e.g.
+// {"who": "Twain, Mark", "2": "Huckleberry Finn", "x_ark": "..."}.
+// See SetFields.
+func Set(dName string, fields map[string]string) ([]*SetResult, error) {
+	return SetFields(dName, fields, AddValue)
+}
+
+// SetFields sets several fields of a directory using mode. Fields may
+// be tag numbers, human names (e.g. "who"), "note" or extension names
+// (e.g. "x_ark"). Every field name is checked before anything is
+// written. The results are in tag order, type first. Use a Tx when the
+// fields must be written all or nothing.
+func SetFields(dName string, fields map[string]string, mode SetMode) ([]*SetResult, error) {
+	tags := map[string]string{}
+	labels := []string{}
+	for field, value := range fields {
+		tag, err := fieldTag(field)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tags[tag]; ok {
+			return nil, fmt.Errorf("%q is given more than once", field)
+		}
+		tags[tag] = value
+		labels = append(labels, tag)
+	}
+	sort.Strings(labels)
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDir(store, sName); err != nil {
+		return nil, err
+	}
+	results := []*SetResult{}
+	for _, tag := range labels {
+		result, err := setTag(dName, tag, tags[tag], mode)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,109 @@
+package namaste
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetValue(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("setmode-test")
+	dName := mem + "setmode-test"
+
+	result, err := SetValue(dName, "who", "Twain,M.", AddValue)
+	if err != nil || result.Filename != "1=Twain,M." || strings.Join(result.Created, ",") != "1=Twain,M." || len(result.Removed) != 0 {
+		t.Fatalf("SetValue() add, unexpected %+v, %v", result, err)
+	}
+	result, err = SetValue(dName, "1", "Clemens, Samuel", AddValue)
+	if err != nil || len(result.Created) != 1 || len(result.Removed) != 0 {
+		t.Errorf("SetValue() add, unexpected %+v, %v", result, err)
+	}
+	// Writing an existing tag creates nothing
+	result, err = SetValue(dName, "who", "Twain,M.", AddValue)
+	if err != nil || result.Filename != "1=Twain,M." || len(result.Created) != 0 {
+		t.Errorf("SetValue() add existing, unexpected %+v, %v", result, err)
+	}
+
+	// A tag of the same name holding a different value isn't overwritten
+	store.Write("setmode-test", "1=Twain,M.", []byte("Twain, M.\n"))
+	if result, err := SetValue(dName, "who", "Twain,M.", AddValue); err == nil {
+		t.Errorf("expected an error adding over a different value, got %+v", result)
+	}
+	if src, _ := store.Read("setmode-test", "1=Twain,M."); string(src) != "Twain, M.\n" {
+		t.Errorf("expected the contents unchanged, got %q", src)
+	}
+
+	// Replace removes the other who tags and leaves the rest
+	Where(dName, "Hannibal, Missouri")
+	result, err = SetValue(dName, "who", "Twain, Mark", ReplaceValue)
+	if err != nil || strings.Join(result.Created, ",") != "1=Twain,^20Mark" || strings.Join(result.Removed, ",") != "1=Clemens,^20Samuel,1=Twain,M." {
+		t.Errorf("SetValue() replace, unexpected %+v, %v", result, err)
+	}
+	tags, _ := Get(dName, nil)
+	if len(tags) != 2 || tags[0].Raw != "1=Twain,^20Mark" || tags[1].Raw != "4=Hannibal,^20Missouri" {
+		t.Errorf("unexpected tags after replace %+v", tags)
+	}
+
+	// Set if absent only writes a missing field
+	result, err = SetValue(dName, "who", "Someone Else", SetIfAbsent)
+	if err != nil || result.Filename != "" || len(result.Created) != 0 || len(result.Removed) != 0 {
+		t.Errorf("SetValue() set-if-absent, unexpected %+v, %v", result, err)
+	}
+	result, err = SetValue(dName, "x_ark", "ark:13030/tf5p30086k", SetIfAbsent)
+	if err != nil || result.Filename != "x_ark=ark^3A13030^2Ftf5p30086k" || len(result.Created) != 1 {
+		t.Errorf("SetValue() set-if-absent, unexpected %+v, %v", result, err)
+	}
+
+	if _, err := SetValue(dName, "author", "Twain", AddValue); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+	for _, s := range []string{"add", "replace", "set-if-absent"} {
+		if mode, err := ParseSetMode(s); err != nil || mode.String() != s {
+			t.Errorf("ParseSetMode(%q) returned %s, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseSetMode("append"); err == nil {
+		t.Errorf("expected an error for an unknown mode")
+	}
+}
+
+func TestSet(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("set-test")
+	dName := mem + "set-test"
+	results, err := Set(dName, map[string]string{
+		"x_ark": "ark:13030/tf5p30086k",
+		"note":  "Scanned\nat 600dpi",
+		"who":   "Twain, Mark",
+		"0":     "bagit_1.0",
+		"WHAT":  "Huckleberry Finn",
+	})
+	if err != nil {
+		t.Fatalf("Set() failed, %s", err)
+	}
+	l := []string{}
+	for _, result := range results {
+		l = append(l, result.Filename)
+	}
+	note := "note=Scanned" + hashMarker + valueHash("Scanned\nat 600dpi") + Ellipsis
+	expected := "0=bagit_1.0,1=Twain,^20Mark,2=Huckleberry^20Finn," + note + ",x_ark=ark^3A13030^2Ftf5p30086k"
+	if s := strings.Join(l, ","); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+
+	// Nothing is written if a field isn't known
+	if _, err := Set(dName, map[string]string{"where": "Hannibal", "author": "Twain"}); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+	if names, _ := FindAll(dName, "where"); len(names) != 0 {
+		t.Errorf("expected no where tags, got %+v", names)
+	}
+	if _, err := Set(dName, map[string]string{"who": "Twain", "1": "Clemens"}); err == nil {
+		t.Errorf("expected an error for a field given twice")
+	}
+
+	results, err = SetFields(dName, map[string]string{"who": "Clemens, Samuel"}, ReplaceValue)
+	if err != nil || len(results) != 1 || strings.Join(results[0].Removed, ",") != "1=Twain,^20Mark" {
+		t.Errorf("SetFields() replace, unexpected %+v, %v", results, err)
+	}
+}
@@ -0,0 +1,147 @@
+package namaste
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is the storage a namaste directory lives in, e.g. local
+// disc, an in-memory store or an object store like S3. Directory
+// names are relative to the store and tag names are the bare
+// filenames found in a directory.
+type Store interface {
+	// Stat returns information about a directory, an error is
+	// returned if it does not exist.
+	Stat(dName string) (fs.FileInfo, error)
+	// List returns the names of the entries in a directory
+	List(dName string) ([]string, error)
+	// Read returns the contents of a tag file
+	Read(dName, name string) ([]byte, error)
+	// Write creates or replaces a tag file. It must be atomic, an
+	// interrupted Write leaves the old contents or the new ones and
+	// never a partial file.
+	Write(dName, name string, src []byte) error
+	// Remove deletes a tag file
+	Remove(dName, name string) error
+}
+
+// DirStore is implemented by stores that hold nested directories,
+// it is needed to Walk a store.
+type DirStore interface {
+	// Dirs returns the names of the subdirectories of dName. Links
+	// to directories are included only if followLinks is true.
+	Dirs(dName string, followLinks bool) ([]string, error)
+	// ListDir returns the names of the entries in a directory, as
+	// List, and the names of its subdirectories, as Dirs, from a
+	// single listing
+	ListDir(dName string, followLinks bool) ([]string, []string, error)
+}
+
+// StreamStore is implemented by stores that can list a directory
+// a batch at a time rather than loading every name into memory.
+type StreamStore interface {
+	// ListEach calls fn with the name of each entry in a directory,
+	// stopping if fn returns an error.
+	ListEach(dName string, fn func(name string) error) error
+}
+
+var (
+	storesMutex = new(sync.RWMutex)
+	// stores maps a URL scheme to its Store, e.g. "s3" for s3://
+	stores = map[string]Store{}
+
+	// localStore is used for directory names without a scheme
+	localStore Store = new(LocalStore)
+)
+
+// RegisterStore associates a Store with a URL scheme. Directory names
+// starting with the scheme (e.g. "mem://item1") are then resolved
+// against that store. Registering a nil store removes the scheme.
+func RegisterStore(scheme string, store Store) {
+	storesMutex.Lock()
+	defer storesMutex.Unlock()
+	if store == nil {
+		delete(stores, scheme)
+		return
+	}
+	stores[scheme] = store
+}
+
+// OpenStore returns the Store and the directory name within that
+// store for dName. Names without a registered scheme are local.
+func OpenStore(dName string) (Store, string, error) {
+	if i := strings.Index(dName, "://"); i > 0 {
+		scheme := dName[0:i]
+		storesMutex.RLock()
+		store, ok := stores[scheme]
+		storesMutex.RUnlock()
+		if ok == false {
+			return nil, "", fmt.Errorf("%q storage is not supported", scheme)
+		}
+		return store, strings.TrimSuffix(dName[i+3:], "/"), nil
+	}
+	return localStore, dName, nil
+}
+
+// listEach calls fn for each entry in a directory, streaming the
+// entries when the store supports it.
+func listEach(store Store, dName string, fn func(name string) error) error {
+	if s, ok := store.(StreamStore); ok {
+		return s.ListEach(dName, fn)
+	}
+	names, err := store.List(dName)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := fn(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDir returns an error if dName is not a directory in store
+func checkDir(store Store, dName string) error {
+	dInfo, err := store.Stat(dName)
+	if err != nil {
+		return err
+	}
+	if dInfo.IsDir() == false {
+		return fmt.Errorf("%q is not a directory", dName)
+	}
+	return nil
+}
+
+// splitBucket splits BUCKET/PREFIX into bucket and prefix
+func splitBucket(dName string) (string, string) {
+	dName = strings.Trim(dName, "/")
+	if i := strings.Index(dName, "/"); i >= 0 {
+		return dName[0:i], dName[i+1:]
+	}
+	return dName, ""
+}
+
+// objectKey returns the object key for a name under a prefix
+func objectKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// dirInfo describes a directory for stores without one, e.g. a
+// prefix in an object store.
+type dirInfo struct {
+	name string
+}
+
+func (d *dirInfo) Name() string       { return d.name }
+func (d *dirInfo) Size() int64        { return 0 }
+func (d *dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0775 }
+func (d *dirInfo) ModTime() time.Time { return time.Time{} }
+func (d *dirInfo) IsDir() bool        { return true }
+func (d *dirInfo) Sys() interface{}   { return nil }
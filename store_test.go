@@ -0,0 +1,225 @@
+package namaste
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestOpenStore(t *testing.T) {
+	store, dName, err := OpenStore("mem://" + testPath + "/")
+	if err != nil {
+		t.Errorf("OpenStore() failed, %s", err)
+		t.FailNow()
+	}
+	if store != testStore || dName != testPath {
+		t.Errorf("expected test store and %q, got %T and %q", testPath, store, dName)
+	}
+	store, dName, err = OpenStore("/tmp/collection/item1")
+	if err != nil {
+		t.Errorf("OpenStore() failed, %s", err)
+		t.FailNow()
+	}
+	if _, ok := store.(*LocalStore); ok == false || dName != "/tmp/collection/item1" {
+		t.Errorf("expected local store and %q, got %T and %q", "/tmp/collection/item1", store, dName)
+	}
+	if _, _, err := OpenStore("ftp://example.edu/item1"); err == nil {
+		t.Errorf("expected an error for an unregistered scheme")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	RegisterStore("memtest", store)
+	defer RegisterStore("memtest", nil)
+
+	if _, err := Who("memtest://item1", "Twain, Mark"); err == nil {
+		t.Errorf("expected an error writing to a missing directory")
+	}
+	store.Mkdir("item1")
+	if _, err := Who("memtest://item1", "Twain, Mark"); err != nil {
+		t.Errorf("Who() failed, %s", err)
+	}
+	if _, err := What("memtest://item1", "Huckleberry Finn"); err != nil {
+		t.Errorf("What() failed, %s", err)
+	}
+	names, err := store.List("item1")
+	if err != nil {
+		t.Errorf("List() failed, %s", err)
+	}
+	if len(names) != 2 || names[0] != "1=Twain,^20Mark" || names[1] != "2=Huckleberry^20Finn" {
+		t.Errorf("unexpected names %+v", names)
+	}
+	src, err := store.Read("item1", "1=Twain,^20Mark")
+	if err != nil || string(src) != "Twain, Mark\n" {
+		t.Errorf("expected %q, got %q, %v", "Twain, Mark\n", src, err)
+	}
+	if err := store.Remove("item1", "1=Twain,^20Mark"); err != nil {
+		t.Errorf("Remove() failed, %s", err)
+	}
+	if err := store.Remove("item1", "1=Twain,^20Mark"); err == nil {
+		t.Errorf("expected an error removing a missing tag")
+	}
+	tags, err := Get("memtest://item1", nil)
+	if err != nil {
+		t.Errorf("Get() failed, %s", err)
+	}
+	if len(tags) != 1 || tags[0].Value != "Huckleberry Finn" {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	dName := t.TempDir()
+	if _, err := DirType(dName, "bagit_0.1"); err != nil {
+		t.Errorf("DirType(%q) failed, %s", dName, err)
+	}
+	src, err := os.ReadFile(path.Join(dName, "0=bagit_0.1"))
+	if err != nil || string(src) != "bagit_0.1\n" {
+		t.Errorf("expected %q, got %q, %v", "bagit_0.1\n", src, err)
+	}
+	fName := path.Join(dName, "README.md")
+	if err := os.WriteFile(fName, []byte("hello\n"), 0664); err != nil {
+		t.Errorf("Can't write %q, %s", fName, err)
+	}
+	tags, err := Get(dName, nil)
+	if err != nil {
+		t.Errorf("Get(%q) failed, %s", dName, err)
+	}
+	if len(tags) != 1 || tags[0].Raw != "0=bagit_0.1" {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+	if _, err := Get(fName, nil); err == nil {
+		t.Errorf("expected an error for %q, not a directory", fName)
+	}
+}
+
+// countingStore counts how often a directory is listed
+type countingStore struct {
+	*MemoryStore
+	lists int
+}
+
+func (store *countingStore) List(dName string) ([]string, error) {
+	store.lists++
+	return store.MemoryStore.List(dName)
+}
+
+func (store *countingStore) Dirs(dName string, followLinks bool) ([]string, error) {
+	store.lists++
+	return store.MemoryStore.Dirs(dName, followLinks)
+}
+
+func (store *countingStore) ListDir(dName string, followLinks bool) ([]string, []string, error) {
+	store.lists++
+	return store.MemoryStore.ListDir(dName, followLinks)
+}
+
+func TestGetListsOnce(t *testing.T) {
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	RegisterStore("counttest", store)
+	defer RegisterStore("counttest", nil)
+	store.Mkdir("item1")
+	DirType("counttest://item1", "bagit_0.97")
+	Who("counttest://item1", "Twain, Mark")
+	Note("counttest://item1", "scanned")
+	Extension("counttest://item1", "source", "gutenberg")
+	store.lists = 0
+	tags, err := Get("counttest://item1", nil)
+	if err != nil {
+		t.Errorf("Get() failed, %s", err)
+	}
+	if store.lists != 1 {
+		t.Errorf("expected the directory to be listed once, listed %d times", store.lists)
+	}
+	expected := []string{"0", "1", "note", "x_source"}
+	if len(tags) != len(expected) {
+		t.Errorf("expected %d tags, got %+v", len(expected), tags)
+		t.FailNow()
+	}
+	for i, name := range expected {
+		if tags[i].Name != name {
+			t.Errorf("expected %q, got %q", name, tags[i].Name)
+		}
+	}
+}
+
+func TestLocalStoreListEach(t *testing.T) {
+	dName := t.TempDir()
+	n := listBatchSize*2 + 10
+	for i := 0; i < n; i++ {
+		fName := path.Join(dName, fmt.Sprintf("page%05d.tif", i))
+		if err := os.WriteFile(fName, []byte{}, 0664); err != nil {
+			t.Errorf("Can't write %q, %s", fName, err)
+			t.FailNow()
+		}
+	}
+	What(dName, "Page images")
+	DirType(dName, "bagit_0.97")
+	count := 0
+	if err := new(LocalStore).ListEach(dName, func(name string) error {
+		count++
+		return nil
+	}); err != nil {
+		t.Errorf("ListEach(%q) failed, %s", dName, err)
+	}
+	if count != n+2 {
+		t.Errorf("expected %d entries, got %d", n+2, count)
+	}
+	tags, err := Get(dName, nil)
+	if err != nil {
+		t.Errorf("Get(%q) failed, %s", dName, err)
+	}
+	if len(tags) != 2 || tags[0].Name != "0" || tags[1].Value != "Page images" {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+}
+
+func TestLocalStoreAtomicWrite(t *testing.T) {
+	dName := t.TempDir()
+	store := new(LocalStore)
+	for _, s := range []string{"Twain, M.\n", "Twain, Mark\n"} {
+		if err := store.Write(dName, "1=Twain,^20Mark", []byte(s)); err != nil {
+			t.Fatalf("Write() failed, %s", err)
+		}
+		if src, err := store.Read(dName, "1=Twain,^20Mark"); err != nil || string(src) != s {
+			t.Errorf("expected %q, got %q, %v", s, src, err)
+		}
+	}
+	// Only the tag file remains
+	names, _ := store.List(dName)
+	if len(names) != 1 || names[0] != "1=Twain,^20Mark" {
+		t.Errorf("expected only the tag file, got %+v", names)
+	}
+	if err := store.Write(path.Join(dName, "missing"), "1=Twain", []byte("Twain\n")); err == nil {
+		t.Errorf("expected an error writing to a missing directory")
+	}
+
+	// A temporary file left by a killed write isn't a tag
+	os.WriteFile(path.Join(dName, tempPrefix+"99-1.tmp"), []byte("Tw"), 0664)
+	tags, err := Get(dName, nil)
+	if err != nil || len(tags) != 1 {
+		t.Errorf("expected one tag, got %+v, %v", tags, err)
+	}
+	report, err := Validate(dName)
+	if err != nil || len(report.Findings) != 1 || report.Findings[0].Code != "temporary-file" {
+		t.Errorf("expected a temporary-file finding, got %+v, %v", report, err)
+	}
+}
+
+// newTestStore registers an empty memory store for a test and returns
+// it with the prefix naming its directories, e.g. "testtx://"
+func newTestStore(t *testing.T) (*MemoryStore, string) {
+	scheme := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, strings.ToLower(t.Name()))
+	store := NewMemoryStore()
+	RegisterStore(scheme, store)
+	t.Cleanup(func() { RegisterStore(scheme, nil) })
+	return store, scheme + "://"
+}
@@ -0,0 +1,107 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	fieldLabels = map[string]string{
+		"0": "type",
+		"1": "who",
+		"2": "what",
+		"3": "when",
+		"4": "where",
+	}
+)
+
+// Tag is a namaste tag parsed from a filename, e.g. "1=Twain,^20M."
+type Tag struct {
+	// Field is the tag number, 0 (type) through 4 (where), or -1
+	// for note and extension tags
+	Field int `json:"field"`
+	// Name is the tag name as used in the filename, e.g. "1", "note" or "x_ark"
+	Name string `json:"name"`
+	// Label is the human readable tag name, e.g. "who", "note" or "x_ark"
+	Label string `json:"label"`
+	// Value is the decoded value. When the filename is truncated
+	// Value ends with Ellipsis unless the full value has been read
+	// from the tag file's contents.
+	Value string `json:"value"`
+	// Raw is the filename the tag was parsed from
+	Raw string `json:"filename"`
+	// Truncated is true if the filename holds a shortened value
+	Truncated bool `json:"truncated,omitempty"`
+	// Transliterated is true if the filename holds an ASCII spelling
+	// of the value and Value was read from the tag file's contents
+	Transliterated bool `json:"transliterated,omitempty"`
+}
+
+// ParseTag parses a namaste filename into a Tag. An error is returned
+// if the filename is not a namaste tag.
+func ParseTag(filename string) (Tag, error) {
+	name, s, ok := splitNamaste(filename)
+	if ok == false {
+		return Tag{}, fmt.Errorf("%q is not a namaste tag", filename)
+	}
+	tag := Tag{
+		Field:     -1,
+		Name:      name,
+		Label:     name,
+		Value:     untruncate(s),
+		Raw:       filename,
+		Truncated: isTruncated(s),
+	}
+	if len(name) == 1 {
+		tag.Field = int(name[0] - '0')
+	}
+	if label, ok := fieldLabels[name]; ok {
+		tag.Label = label
+	}
+	return tag, nil
+}
+
+// Filename returns the namaste filename for the tag. A truncated tag
+// returns the filename it was parsed from while Value is the value
+// read from it.
+func (tag Tag) Filename() string {
+	if tag.Truncated && tag.Raw != "" && (tag.Value == Decode(tag.Raw) || isTruncationOf(tag.Name, tag.Value, tag.Raw)) {
+		return tag.Raw
+	}
+	if tag.Transliterated && tag.Raw != "" && isTransliteration(tag.Name, tag.Value, tag.Raw) {
+		return tag.Raw
+	}
+	return Encode(tag.Name, tag.Value)
+}
+
+// String returns the tag's filename
+func (tag Tag) String() string {
+	return tag.Filename()
+}
+
+// splitNamaste splits a namaste filename into its tag label and
+// encoded value. The label must be a single digit, "note" or an
+// extension name (e.g. x_ark).
+func splitNamaste(name string) (string, string, bool) {
+	parts := strings.SplitN(name, "=", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	label := parts[0]
+	if (len(label) == 1 && label[0] >= '0' && label[0] <= '9') || label == "note" || isExtension(label) {
+		return label, parts[1], true
+	}
+	return "", "", false
+}
+
+// parseTags parses a list of namaste filenames skipping any
+// that are not namaste tags.
+func parseTags(names []string) []Tag {
+	tags := []Tag{}
+	for _, name := range names {
+		if tag, err := ParseTag(name); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
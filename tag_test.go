@@ -0,0 +1,54 @@
+package namaste
+
+import (
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	expected := map[string]Tag{
+		"0=bagit_0.1": Tag{
+			Field: 0, Name: "0", Label: "type", Value: "bagit_0.1", Raw: "0=bagit_0.1",
+		},
+		"1=Twain,^20M.": Tag{
+			Field: 1, Name: "1", Label: "who", Value: "Twain, M.", Raw: "1=Twain,^20M.",
+		},
+		"2=Huckleberry...": Tag{
+			Field: 2, Name: "2", Label: "what", Value: "Huckleberry...", Raw: "2=Huckleberry...", Truncated: true,
+		},
+		"note=a^20note": Tag{
+			Field: -1, Name: "note", Label: "note", Value: "a note", Raw: "note=a^20note",
+		},
+		"x_ark=ark^3A^2F12345": Tag{
+			Field: -1, Name: "x_ark", Label: "x_ark", Value: "ark:/12345", Raw: "x_ark=ark^3A^2F12345",
+		},
+	}
+	for name, expect := range expected {
+		tag, err := ParseTag(name)
+		if err != nil {
+			t.Errorf("ParseTag(%q) failed, %s", name, err)
+			continue
+		}
+		if tag != expect {
+			t.Errorf("expected %+v, got %+v", expect, tag)
+		}
+		if tag.Filename() != name {
+			t.Errorf("expected filename %q, got %q", name, tag.Filename())
+		}
+	}
+	for _, name := range []string{"README.md", "bagit.txt", "x_=oops", "ab=cd"} {
+		if _, err := ParseTag(name); err == nil {
+			t.Errorf("expected ParseTag(%q) to fail", name)
+		}
+	}
+
+	// A truncated tag with its full value recovered encodes back
+	// to the truncated name.
+	saveMax := MaxNameLength
+	defer func() { MaxNameLength = saveMax }()
+	MaxNameLength = 16
+	tag, _ := ParseTag("2=Huckleberry...")
+	tag.Value = "Huckleberry Finn"
+	if tag.Filename() != "2=Huckleberry..." {
+		t.Errorf("expected %q, got %q", "2=Huckleberry...", tag.Filename())
+	}
+}
@@ -0,0 +1,103 @@
+package namaste
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// TransliterateNames writes ASCII only filenames. Accented letters
+	// lose their accents and other letters are spelled out (e.g. "ß"
+	// becomes "ss"), anything left is ^ escaped as UTF-8 bytes. The
+	// tag file's contents keep the exact value. Get reads the contents
+	// of ASCII filenames to report it only when TransliterateNames is
+	// set, so set it when reading such directories too.
+	TransliterateNames = false
+
+	// transliterations spell out characters that don't decompose to
+	// ASCII (see norm.NFKD)
+	transliterations = map[rune]string{
+		'ß': "ss", 'ẞ': "SS", 'æ': "ae", 'Æ': "AE", 'œ': "oe", 'Œ': "OE",
+		'ø': "o", 'Ø': "O", 'đ': "d", 'Đ': "D", 'ð': "d", 'Ð': "D",
+		'þ': "th", 'Þ': "Th", 'ł': "l", 'Ł': "L", 'ı': "i", 'ŋ': "ng",
+		'Ŋ': "NG", 'ħ': "h", 'Ħ': "H", 'ŧ': "t", 'Ŧ': "T", 'ĸ': "k",
+		'‘': "'", '’': "'", '‚': "'", '‛': "'", '“': "\"", '”': "\"",
+		'„': "\"", '‟': "\"", '‹': "<", '›': ">", '«': "<<", '»': ">>",
+		'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-",
+		'−': "-", '…': "...", '•': "*", '·': ".", ' ': " ",
+		'©': "(C)", '®': "(R)", '™': "TM", '°': "deg", '×': "x",
+		'€': "EUR", '£': "GBP", '¥': "JPY",
+	}
+)
+
+// Transliterate returns an ASCII spelling of s where one is known,
+// e.g. "Gödel, Straße" becomes "Godel, Strasse". Characters without
+// one (e.g. "日本") are left as is.
+func Transliterate(s string) string {
+	var sb strings.Builder
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case r < utf8.RuneSelf:
+			sb.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// Drop accents and other combining marks
+		default:
+			if t, ok := transliterations[r]; ok {
+				sb.WriteString(t)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return norm.NFC.String(sb.String())
+}
+
+// isASCII returns true if s holds only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// isTransliteration returns true if name is the transliterated
+// filename of a tag whose value is contents
+func isTransliteration(tag, contents, name string) bool {
+	if isASCII(contents) {
+		return false
+	}
+	for _, profile := range encodingProfiles {
+		if encodeASCIIName(profile, tag, contents) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readTransliterated sets the value of the tags whose filename is a
+// transliteration to the exact value held in the tag file. Nothing is
+// read unless TransliterateNames is set.
+func readTransliterated(dName string, tags []Tag) ([]Tag, error) {
+	if TransliterateNames == false {
+		return tags, nil
+	}
+	for i, tag := range tags {
+		if tag.Truncated || isASCII(tag.Raw) == false {
+			continue
+		}
+		contents, err := readNamaste(dName, tag.Raw)
+		if err != nil {
+			return nil, err
+		}
+		if isTransliteration(tag.Name, contents, tag.Raw) {
+			tags[i].Value = contents
+			tags[i].Transliterated = true
+		}
+	}
+	return tags, nil
+}
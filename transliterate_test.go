@@ -0,0 +1,101 @@
+package namaste
+
+import (
+	"fmt"
+	"testing"
+)
+
+// readingStore counts the tag files read and can fail to read them
+type readingStore struct {
+	*MemoryStore
+	reads int
+	fail  bool
+}
+
+func (store *readingStore) Read(dName, name string) ([]byte, error) {
+	store.reads++
+	if store.fail {
+		return nil, fmt.Errorf("connection reset")
+	}
+	return store.MemoryStore.Read(dName, name)
+}
+
+func TestTransliterate(t *testing.T) {
+	values := map[string]string{
+		"Gödel, Straße":       "Godel, Strasse",
+		"Łódź":                "Lodz",
+		"Ærø “quoted” – text": "AEro \"quoted\" - text",
+		"日本":                  "日本",
+		"Twain, Mark":         "Twain, Mark",
+	}
+	for value, expected := range values {
+		if s := Transliterate(value); s != expected {
+			t.Errorf("Transliterate(%q) expected %q, got %q", value, expected, s)
+		}
+	}
+}
+
+func TestTransliterateNames(t *testing.T) {
+	TransliterateNames = true
+	defer func() { TransliterateNames = false }()
+
+	if s := Encode("who", "Gödel, Kurt"); s != "1=Godel,^20Kurt" {
+		t.Errorf("expected %q, got %q", "1=Godel,^20Kurt", s)
+	}
+	// Characters without an ASCII spelling are escaped as UTF-8 bytes
+	if s := Encode("what", "日本"); s != "2=^E6^97^A5^E6^9C^AC" {
+		t.Errorf("expected %q, got %q", "2=^E6^97^A5^E6^9C^AC", s)
+	}
+
+	store := &readingStore{MemoryStore: NewMemoryStore()}
+	RegisterStore("translittest", store)
+	defer RegisterStore("translittest", nil)
+	store.Mkdir("item1")
+	dName := "translittest://item1"
+	name, err := Who(dName, "Gödel, Kurt")
+	if err != nil || name != "1=Godel,^20Kurt" {
+		t.Fatalf("Who() expected %q, got %q, %v", "1=Godel,^20Kurt", name, err)
+	}
+	if src, _ := store.Read("item1", name); string(src) != "Gödel, Kurt\n" {
+		t.Errorf("expected the exact value in the contents, got %q", src)
+	}
+
+	// The contents are authoritative when reading
+	tags, err := Get(dName, []string{"who"})
+	if err != nil || len(tags) != 1 {
+		t.Fatalf("Get() expected one tag, got %+v, %v", tags, err)
+	}
+	tag := tags[0]
+	if tag.Value != "Gödel, Kurt" || tag.Transliterated == false {
+		t.Errorf("expected a transliterated tag holding %q, got %+v", "Gödel, Kurt", tag)
+	}
+	if s := tag.Filename(); s != name {
+		t.Errorf("expected filename %q, got %q", name, s)
+	}
+	store.fail = true
+	if _, err := Get(dName, nil); err == nil {
+		t.Errorf("expected Get() to report the read error")
+	}
+	store.fail = false
+
+	// Nothing is read unless TransliterateNames is set
+	TransliterateNames = false
+	store.reads = 0
+	tags, err = Get(dName, nil)
+	if err != nil || len(tags) != 1 || tags[0].Value != "Godel, Kurt" || store.reads != 0 {
+		t.Errorf("expected the filename's value without reading, got %+v, %d reads, %v", tags, store.reads, err)
+	}
+
+	// Transliterated names aren't reported as mismatched or repaired
+	report, err := Validate(dName)
+	if err != nil {
+		t.Fatalf("Validate() failed, %s", err)
+	}
+	if len(report.Findings) != 0 {
+		t.Errorf("expected no findings, got %+v", report.Findings)
+	}
+	plan, err := PlanRepair(dName, FromContents)
+	if err != nil || len(plan.Actions) != 0 {
+		t.Errorf("expected nothing to repair, got %+v, %v", plan, err)
+	}
+}
@@ -0,0 +1,181 @@
+package namaste
+
+import (
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Ellipsis marks a tag filename whose value has been shortened
+	// per Section 4 of the Namaste Spec. The full value is kept in
+	// the tag file's contents.
+	Ellipsis = "..."
+
+	// escapedEllipsis is used when a value really ends in "..." so
+	// it can't be confused with a truncated filename. Profiles with
+	// TrailingDots end truncated filenames with it after the hash as
+	// Windows drops the periods of a filename ending in Ellipsis.
+	escapedEllipsis = "..^2E"
+
+	// hashMarker starts the hash of the full value written before the
+	// Ellipsis of a truncated filename, so values sharing a prefix get
+	// different filenames. Encoded values never hold a "^" that isn't
+	// followed by two hex digits so it can't be mistaken for the value.
+	hashMarker = "^h"
+
+	// hashLength is the number of hex digits in the hash
+	hashLength = 8
+)
+
+var (
+	// MaxNameLength is the maximum length in bytes of a namaste tag
+	// filename. Longer filenames are truncated and end with Ellipsis.
+	// The default matches NAME_MAX on most filesystems. A value less
+	// than or equal to zero disables truncation. It must leave room
+	// for the tag label and the hash and Ellipsis of a truncated name,
+	// filenames that don't fit aren't written.
+	MaxNameLength = 255
+)
+
+// checkNameLength returns an error if a filename is longer than
+// MaxNameLength, see shorten
+func checkNameLength(name string) error {
+	if MaxNameLength > 0 && len(name) > MaxNameLength {
+		return fmt.Errorf("MaxNameLength %d is too small for %q", MaxNameLength, name)
+	}
+	return nil
+}
+
+// valueHash returns the hash of a full value written in a truncated
+// filename
+func valueHash(value string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(value)))
+}
+
+// isHash returns true if s is a hash written by valueHash
+func isHash(s string) bool {
+	if len(s) != hashLength {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if unhex(s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// splitHash splits the hash written by shorten from the end of s, ok
+// is false if s doesn't end with a hash
+func splitHash(s string) (string, string, bool) {
+	if i := len(s) - len(hashMarker) - hashLength; i >= 0 && s[i:i+len(hashMarker)] == hashMarker && isHash(s[i+len(hashMarker):]) {
+		return s[0:i], s[i+len(hashMarker):], true
+	}
+	return s, "", false
+}
+
+// splitTruncated splits a truncated encoded value into the shortened
+// value and the hash of the full value. The hash is empty for names
+// truncated before hashes were written. ok is false if s isn't
+// truncated.
+func splitTruncated(s string) (string, string, bool) {
+	if strings.HasSuffix(s, escapedEllipsis) {
+		// Truncated by a profile with TrailingDots
+		if value, hash, ok := splitHash(s[0 : len(s)-len(escapedEllipsis)]); ok {
+			return value, hash, true
+		}
+		return s, "", false
+	}
+	if strings.HasSuffix(s, Ellipsis) == false {
+		return s, "", false
+	}
+	value, hash, _ := splitHash(s[0 : len(s)-len(Ellipsis)])
+	return value, hash, true
+}
+
+// isTruncated returns true if the encoded name ends with the
+// truncation marker.
+func isTruncated(s string) bool {
+	_, _, ok := splitTruncated(s)
+	return ok
+}
+
+// escapeEllipsis escapes the last period of an encoded value ending
+// in "..." so it isn't read as truncated
+func escapeEllipsis(s string) string {
+	if strings.HasSuffix(s, Ellipsis) {
+		return s[0:len(s)-len(Ellipsis)] + escapedEllipsis
+	}
+	return s
+}
+
+// truncate shortens an encoded value so that prefix plus the value
+// fits in MaxNameLength bytes, see shorten. If no truncation is needed
+// a value ending in "..." has its last period escaped.
+func truncate(prefix, s, value string, trailingDots bool) string {
+	s = escapeEllipsis(s)
+	if MaxNameLength <= 0 || len(prefix)+len(s) <= MaxNameLength {
+		return s
+	}
+	return shorten(prefix, s, value, trailingDots)
+}
+
+// shorten cuts an encoded value so that prefix plus the value, the
+// hash of the full value and Ellipsis fit in MaxNameLength bytes. It
+// won't split a ^XX escape or a multibyte UTF-8 character. With
+// trailingDots the last period of the Ellipsis is escaped. If
+// MaxNameLength can't hold prefix and the marker the result is longer
+// than MaxNameLength and checkNameLength rejects it.
+func shorten(prefix, s, value string, trailingDots bool) string {
+	marker := hashMarker + valueHash(value) + Ellipsis
+	if trailingDots {
+		marker = hashMarker + valueHash(value) + escapedEllipsis
+	}
+	i := len(s)
+	if MaxNameLength > 0 && len(prefix)+len(s)+len(marker) > MaxNameLength {
+		i = MaxNameLength - len(prefix) - len(marker)
+	}
+	if i < 0 {
+		i = 0
+	}
+	// Back up to the start of a UTF-8 character
+	for i > 0 && i < len(s) && utf8.RuneStart(s[i]) == false {
+		i--
+	}
+	// Avoid splitting a ^XX escape sequence
+	if j := strings.LastIndex(s[0:i], "^"); j >= 0 && i-j < 3 {
+		i = j
+	}
+	return s[0:i] + marker
+}
+
+// readTruncated sets the value of truncated tags to the full value
+// held in the tag file's contents. A tag file without contents keeps
+// the value from its filename.
+func readTruncated(dName string, tags []Tag) ([]Tag, error) {
+	for i, tag := range tags {
+		if tag.Truncated == false {
+			continue
+		}
+		contents, err := readNamaste(dName, tag.Raw)
+		if err != nil {
+			return nil, err
+		}
+		if contents != "" {
+			tags[i].Value = contents
+		}
+	}
+	return tags, nil
+}
+
+// untruncate decodes an encoded value. A truncated value is returned
+// decoded with the Ellipsis appended.
+func untruncate(s string) string {
+	if value, _, ok := splitTruncated(s); ok {
+		return charDecode(value) + Ellipsis
+	}
+	// An escapedEllipsis decodes to "..."
+	return charDecode(s)
+}
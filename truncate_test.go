@@ -0,0 +1,223 @@
+package namaste
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	saveMax := MaxNameLength
+	defer func() { MaxNameLength = saveMax }()
+	MaxNameLength = 26
+
+	expected := map[string]string{
+		"Hamlet":           "2=Hamlet",
+		"Huckleberry Finn": "2=Huckleberry^20Finn",
+		"Wait...":          "2=Wait..^2E",
+		"Tom:Sawyer":       "2=Tom^3ASawyer",
+	}
+	// Truncated names end with a hash of the full value and Ellipsis
+	truncated := map[string]string{
+		"Huckleberry Finn Abroad, or Tom": "2=Huckleberry",
+		"Tom Sawyer Abroad, or Huck":      "2=Tom^20Sawye",
+		"Hucklebérry Finn Abroad":         "2=Hucklebérr",
+		"Tom Sawyer^Abroad, or Huck":      "2=Tom^20Sawye",
+	}
+	for val, expect := range truncated {
+		expected[val] = expect + hashMarker + valueHash(val) + Ellipsis
+	}
+	for val, expect := range expected {
+		result := Encode("what", val)
+		if result != expect {
+			t.Errorf("Encode(%q) expected %q, got %q", val, expect, result)
+		}
+		if len(result) > MaxNameLength {
+			t.Errorf("%q is longer than %d bytes", result, MaxNameLength)
+		}
+	}
+
+	// Values sharing a prefix get different names
+	if Encode("what", "Huckleberry Finn Abroad") == Encode("what", "Huckleberry Finn Returns") {
+		t.Errorf("expected different names for values sharing a prefix")
+	}
+
+	// Don't split an escape sequence
+	hash := hashMarker + valueHash("Tom Sawyer Abroad") + Ellipsis
+	MaxNameLength = 19
+	if result := Encode("what", "Tom Sawyer Abroad"); result != "2=Tom"+hash {
+		t.Errorf("expected %q, got %q", "2=Tom"+hash, result)
+	}
+	MaxNameLength = 20
+	if result := Encode("what", "Tom Sawyer Abroad"); result != "2=Tom"+hash {
+		t.Errorf("expected %q, got %q", "2=Tom"+hash, result)
+	}
+
+	// Windows drops trailing periods so the Ellipsis is escaped
+	MaxNameLength = 26
+	SetEncoding("windows")
+	windows := map[string]string{
+		"Tom Sawyer Abroad, or Huck": "2=Tom^20Saw" + hashMarker + valueHash("Tom Sawyer Abroad, or Huck") + escapedEllipsis,
+		"Wait...":                    "2=Wait^2E^2E^2E",
+	}
+	for val, expect := range windows {
+		if result := Encode("what", val); result != expect {
+			t.Errorf("Encode(%q) with windows expected %q, got %q", val, expect, result)
+		}
+	}
+	if IsTruncated(windows["Tom Sawyer Abroad, or Huck"]) == false {
+		t.Errorf("expected %q to be truncated", windows["Tom Sawyer Abroad, or Huck"])
+	}
+	if s := Decode(windows["Tom Sawyer Abroad, or Huck"]); s != "Tom Saw..." {
+		t.Errorf("expected %q, got %q", "Tom Saw...", s)
+	}
+	SetEncoding("namaste")
+
+	decoded := map[string]string{
+		"2=Huckleberr...":           "Huckleberr...",
+		"2=Huckleberr^h0a1b2c3d...": "Huckleberr...",
+		"2=Wait..^2E":               "Wait...",
+		"2=Tom^20Sa...":             "Tom Sa...",
+		"2=Hamlet":                  "Hamlet",
+	}
+	for name, expect := range decoded {
+		if result := Decode(name); result != expect {
+			t.Errorf("Decode(%q) expected %q, got %q", name, expect, result)
+		}
+	}
+	if IsTruncated("2=Wait..^2E") {
+		t.Errorf("expected %q not to be truncated", "2=Wait..^2E")
+	}
+	if IsTruncated("2=Huckleberr...") == false {
+		t.Errorf("expected %q to be truncated", "2=Huckleberr...")
+	}
+}
+
+func TestTruncatedValues(t *testing.T) {
+	title := strings.Repeat("All work and no play makes Jack a dull boy. ", 10)
+	name, err := What(testDir, title)
+	if err != nil {
+		t.Errorf("What(%q, %q) failed, %s", testDir, title, err)
+		t.FailNow()
+	}
+	defer testStore.Remove(testPath, name)
+	if len(name) > MaxNameLength {
+		t.Errorf("expected %q to be at most %d bytes", name, MaxNameLength)
+	}
+	values, err := GetValues(testDir, []string{"what"})
+	if err != nil {
+		t.Errorf("GetValues(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	found := false
+	for _, val := range values {
+		if val.Name == name {
+			found = true
+			if val.Value != title {
+				t.Errorf("expected %q, got %q", title, val.Value)
+			}
+			if val.Truncated == false || val.Mismatch {
+				t.Errorf("expected truncated without mismatch, got %+v", val)
+			}
+		}
+	}
+	if found == false {
+		t.Errorf("missing %q in %+v", name, values)
+	}
+
+	// Recover a truncated type from contents
+	saveMax := MaxNameLength
+	MaxNameLength = 15
+	name, err = DirType(testDir, "dataset_12.345")
+	MaxNameLength = saveMax
+	if err != nil {
+		t.Errorf("DirType(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	defer testStore.Remove(testPath, name)
+	types, err := GetTypes(testDir)
+	if err != nil {
+		t.Errorf("GetTypes(%q) failed, %s", testDir, err)
+		t.FailNow()
+	}
+	if m, ok := types["dataset"]; ok == false {
+		t.Errorf("expected dataset in %+v", types)
+	} else if m["major"] != "12" || m["minor"] != "345" {
+		t.Errorf("expected dataset 12.345, got %+v", m)
+	}
+	src, _ := testStore.Read(testPath, name)
+	if string(src) != "dataset_12.345\n" {
+		t.Errorf("expected full value in contents, got %q", src)
+	}
+}
+
+func TestGetTruncated(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("item1")
+	dName := mem + "item1"
+	title := strings.Repeat("All work and no play makes Jack a dull boy. ", 7)
+	name, err := What(dName, title)
+	if err != nil {
+		t.Fatalf("What() failed, %s", err)
+	}
+	tags, err := Get(dName, []string{"what"})
+	if err != nil || len(tags) != 1 {
+		t.Fatalf("Get() expected one tag, got %+v, %v", tags, err)
+	}
+	tag := tags[0]
+	if tag.Value != title || tag.Truncated == false || tag.Filename() != name {
+		t.Errorf("expected the full value %q from %q, got %+v", title, name, tag)
+	}
+
+	// Without contents the value comes from the filename
+	store.Write("item1", name, []byte{})
+	tags, _ = Get(dName, []string{"what"})
+	if len(tags) != 1 || strings.HasSuffix(tags[0].Value, Ellipsis) == false || tags[0].Filename() != name {
+		t.Errorf("expected the truncated value from %q, got %+v", name, tags)
+	}
+}
+
+func TestTruncatedSharedPrefix(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("item1")
+	dName := mem + "item1"
+	prefix := strings.Repeat("All work and no play makes Jack a dull boy. ", 7)
+	values := []string{prefix + "The Shining", prefix + "Room 237"}
+	for _, value := range values {
+		if _, err := What(dName, value); err != nil {
+			t.Fatalf("What() failed, %s", err)
+		}
+	}
+	tags, err := Get(dName, []string{"what"})
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("Get() expected two tags, got %+v, %v", tags, err)
+	}
+	found := map[string]bool{}
+	for _, tag := range tags {
+		found[tag.Value] = tag.Truncated
+	}
+	for _, value := range values {
+		if found[value] == false {
+			t.Errorf("expected a truncated tag holding %q, got %+v", value, tags)
+		}
+	}
+}
+
+func TestMaxNameLengthTooSmall(t *testing.T) {
+	saveMax := MaxNameLength
+	defer func() { MaxNameLength = saveMax }()
+	store, mem := newTestStore(t)
+	store.Mkdir("item1")
+	dName := mem + "item1"
+
+	// The label and the truncation marker don't fit so nothing is written
+	MaxNameLength = 10
+	if name, err := Note(dName, "hello world"); err == nil {
+		t.Errorf("expected an error writing %q", name)
+	}
+	if _, err := NewTx(dName).Add("note", "hello world").Commit(); err == nil {
+		t.Errorf("expected an error committing a name longer than %d bytes", MaxNameLength)
+	}
+	if names, _ := store.List("item1"); len(names) != 0 {
+		t.Errorf("expected nothing written, got %+v", names)
+	}
+}
@@ -0,0 +1,283 @@
+package namaste
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+)
+
+// Tx stages changes to a directory's tags and applies them together
+// with Commit, all or nothing. A Tx is not safe for concurrent use.
+//
+//	tx := namaste.NewTx("item1")
+//	tx.Replace("who", "Twain, Mark")
+//	tx.Replace("what", "Huckleberry Finn")
+//	tx.RemoveAll("note")
+//	result, err := tx.Commit()
+type Tx struct {
+	dName string
+	ops   []*txOp
+	done  bool
+}
+
+// txOp is a staged change, op is "set", "remove" or "remove-all"
+type txOp struct {
+	op    string
+	field string
+	value string
+	mode  SetMode
+}
+
+// TxResult reports the files changed by Commit
+type TxResult struct {
+	Path    string   `json:"path"`
+	Created []string `json:"created"`
+	Removed []string `json:"removed"`
+}
+
+// txFile is a tag file as Commit plans the changes, contents are read
+// only when needed to match a value
+type txFile struct {
+	tag      string
+	contents string
+	read     bool
+}
+
+// NewTx returns a transaction for the tags of a directory
+func NewTx(dName string) *Tx {
+	return &Tx{dName: dName}
+}
+
+// Set stages setting a field, see SetValue
+func (tx *Tx) Set(field, value string, mode SetMode) *Tx {
+	tx.ops = append(tx.ops, &txOp{op: "set", field: field, value: value, mode: mode})
+	return tx
+}
+
+// Add stages adding a value to a field
+func (tx *Tx) Add(field, value string) *Tx {
+	return tx.Set(field, value, AddValue)
+}
+
+// Replace stages replacing a field's values with value
+func (tx *Tx) Replace(field, value string) *Tx {
+	return tx.Set(field, value, ReplaceValue)
+}
+
+// Remove stages removing the tags of a field holding value, see
+// FindValue
+func (tx *Tx) Remove(field, value string) *Tx {
+	tx.ops = append(tx.ops, &txOp{op: "remove", field: field, value: value})
+	return tx
+}
+
+// RemoveAll stages removing every tag of a field
+func (tx *Tx) RemoveAll(field string) *Tx {
+	tx.ops = append(tx.ops, &txOp{op: "remove-all", field: field})
+	return tx
+}
+
+// Rollback discards the staged changes, nothing has been written
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return fmt.Errorf("transaction is already done")
+	}
+	tx.ops, tx.done = nil, true
+	return nil
+}
+
+// Commit checks every staged change then applies them in the order
+// they were staged. New tag files are written before any are removed.
+// If any write or remove fails the files already changed are restored
+// and the directory is left as it was. As with SetValue adding a value
+// whose tag file holds a different value is an error.
+func (tx *Tx) Commit() (*TxResult, error) {
+	if tx.done {
+		return nil, fmt.Errorf("transaction is already done")
+	}
+	tx.done = true
+
+	// Check everything before touching the directory
+	for _, op := range tx.ops {
+		tag, err := fieldTag(op.field)
+		if err != nil {
+			return nil, err
+		}
+		op.field = tag
+	}
+	store, sName, err := OpenStore(tx.dName)
+	if err != nil {
+		return nil, err
+	}
+	names, err := getNamaste(tx.dName, defaultKinds)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work out the tag files once every change is made
+	files := map[string]*txFile{}
+	existed := map[string]bool{}
+	for _, name := range names {
+		label, _, _ := splitNamaste(name)
+		files[name] = &txFile{tag: label}
+		existed[name] = true
+	}
+	fieldNames := func(tag string) []string {
+		l := []string{}
+		for name, f := range files {
+			if f.tag == tag {
+				l = append(l, name)
+			}
+		}
+		sort.Strings(l)
+		return l
+	}
+	writes := map[string]bool{}
+	for _, op := range tx.ops {
+		switch op.op {
+		case "set":
+			if op.mode == SetIfAbsent && len(fieldNames(op.field)) > 0 {
+				continue
+			}
+			value := NormalizeValue(op.value)
+			name := Encode(op.field, value)
+			if err := checkNameLength(name); err != nil {
+				return nil, err
+			}
+			if op.mode == ReplaceValue {
+				for _, old := range fieldNames(op.field) {
+					delete(files, old)
+					delete(writes, old)
+				}
+			}
+			// Adding doesn't overwrite a tag holding a different value
+			if f, ok := files[name]; ok && op.mode == AddValue {
+				if f.read == false {
+					if f.contents, err = readNamaste(tx.dName, name); err != nil {
+						return nil, err
+					}
+					f.read = true
+				}
+				if f.contents != "" && f.contents != value {
+					return nil, fmt.Errorf("%q already holds a different value, %q", name, f.contents)
+				}
+			}
+			files[name] = &txFile{tag: op.field, contents: value, read: true}
+			writes[name] = true
+		case "remove":
+			value := NormalizeValue(op.value)
+			for _, name := range fieldNames(op.field) {
+				f := files[name]
+				if f.read == false {
+					if f.contents, err = readNamaste(tx.dName, name); err != nil {
+						return nil, err
+					}
+					f.read = true
+				}
+				if holdsValue(name, f.contents, value) {
+					delete(files, name)
+					delete(writes, name)
+				}
+			}
+		case "remove-all":
+			for _, name := range fieldNames(op.field) {
+				delete(files, name)
+				delete(writes, name)
+			}
+		}
+	}
+
+	result := &TxResult{Path: tx.dName, Created: []string{}, Removed: []string{}}
+	for name := range writes {
+		if existed[name] == false {
+			result.Created = append(result.Created, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := files[name]; ok == false {
+			result.Removed = append(result.Removed, name)
+		}
+	}
+	sort.Strings(result.Created)
+
+	// Apply the changes keeping what's needed to undo them
+	changes := &undoLog{store: store, dName: sName}
+	l := []string{}
+	for name := range writes {
+		l = append(l, name)
+	}
+	sort.Strings(l)
+	for _, name := range l {
+		if err := changes.write(name, []byte(terminateValue(files[name].contents))); err != nil {
+			changes.rollback()
+			return nil, fmt.Errorf("commit to %q failed, %s", tx.dName, err)
+		}
+	}
+	for _, name := range result.Removed {
+		if err := changes.remove(name); err != nil {
+			changes.rollback()
+			return nil, fmt.Errorf("commit to %q failed, %s", tx.dName, err)
+		}
+	}
+	return result, nil
+}
+
+// undo is a tag file as it was before a change
+type undo struct {
+	name    string
+	src     []byte
+	existed bool
+}
+
+// undoLog changes the tag files of a directory keeping what's needed
+// to restore them, it is used by Tx and RepairPlan to apply their
+// changes all or nothing
+type undoLog struct {
+	store Store
+	dName string
+	undos []*undo
+}
+
+// write creates or replaces a tag file saving its old contents
+func (changes *undoLog) write(name string, src []byte) error {
+	u := &undo{name: name}
+	old, err := changes.store.Read(changes.dName, name)
+	switch {
+	case err == nil:
+		u.src, u.existed = old, true
+	case errors.Is(err, fs.ErrNotExist) == false:
+		return err
+	}
+	if err := changes.store.Write(changes.dName, name, src); err != nil {
+		return err
+	}
+	changes.undos = append(changes.undos, u)
+	return nil
+}
+
+// remove deletes a tag file saving its contents
+func (changes *undoLog) remove(name string) error {
+	src, err := changes.store.Read(changes.dName, name)
+	if err != nil {
+		return err
+	}
+	if err := changes.store.Remove(changes.dName, name); err != nil {
+		return err
+	}
+	changes.undos = append(changes.undos, &undo{name: name, src: src, existed: true})
+	return nil
+}
+
+// rollback restores the tag files changed, the most recent first
+func (changes *undoLog) rollback() {
+	for i := len(changes.undos) - 1; i >= 0; i-- {
+		u := changes.undos[i]
+		if u.existed {
+			changes.store.Write(changes.dName, u.name, u.src)
+		} else {
+			changes.store.Remove(changes.dName, u.name)
+		}
+	}
+	changes.undos = nil
+}
@@ -0,0 +1,135 @@
+package namaste
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// failingStore fails to write or remove a named tag file
+type failingStore struct {
+	*MemoryStore
+	fail string
+}
+
+func (store *failingStore) Write(dName, name string, src []byte) error {
+	if name == store.fail {
+		return fmt.Errorf("disc full")
+	}
+	return store.MemoryStore.Write(dName, name, src)
+}
+
+func (store *failingStore) Remove(dName, name string) error {
+	if name == store.fail {
+		return fmt.Errorf("permission denied")
+	}
+	return store.MemoryStore.Remove(dName, name)
+}
+
+// listTags returns a directory's tag filenames joined by commas
+func listTags(dName string) string {
+	l, _ := getNamaste(dName, defaultKinds)
+	return strings.Join(l, ",")
+}
+
+func TestTx(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("tx-test")
+	dName := mem + "tx-test"
+	Who(dName, "Twain,M.")
+	Note(dName, "draft")
+
+	tx := NewTx(dName)
+	tx.Add("type", "bagit_1.0").Replace("who", "Twain, Mark").Add("what", "Huckleberry Finn")
+	tx.Add("when", "1884").Add("where", "Hannibal, Missouri").RemoveAll("note")
+	result, err := tx.Commit()
+	if err != nil {
+		t.Fatalf("Commit() failed, %s", err)
+	}
+	expected := "0=bagit_1.0,1=Twain,^20Mark,2=Huckleberry^20Finn,3=1884,4=Hannibal,^20Missouri"
+	if s := strings.Join(result.Created, ","); s != expected {
+		t.Errorf("expected created %q, got %q", expected, s)
+	}
+	if s := strings.Join(result.Removed, ","); s != "1=Twain,M.,note=draft" {
+		t.Errorf("expected removed %q, got %q", "1=Twain,M.,note=draft", s)
+	}
+	if s := listTags(dName); s != expected {
+		t.Errorf("expected tags %q, got %q", expected, s)
+	}
+	if _, err := tx.Commit(); err == nil {
+		t.Errorf("expected an error committing twice")
+	}
+
+	// Changes apply in order, a value added then removed isn't written
+	tx = NewTx(dName)
+	tx.Add("x_ark", "ark:13030/tf5p30086k").Remove("x_ark", "ark:13030/tf5p30086k").Set("who", "Someone Else", SetIfAbsent)
+	if result, err := tx.Commit(); err != nil || len(result.Created) != 0 || len(result.Removed) != 0 {
+		t.Errorf("expected no changes, got %+v, %v", result, err)
+	}
+
+	// A value ending in "..." doesn't match a summarized filename
+	Note(dName, "Checked\nby RSD")
+	Note(dName, "Checked...")
+	result, err = NewTx(dName).Remove("note", "Checked...").Commit()
+	if err != nil || strings.Join(result.Removed, ",") != "note=Checked..^2E" {
+		t.Errorf("expected only %q removed, got %+v, %v", "note=Checked..^2E", result, err)
+	}
+	RemoveAll(dName, "note")
+
+	// Nothing is written if a change isn't valid
+	tx = NewTx(dName).Add("note", "checked").Add("author", "Twain")
+	if _, err := tx.Commit(); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+	if s := listTags(dName); s != expected {
+		t.Errorf("expected tags unchanged, got %q", s)
+	}
+	// Adding doesn't overwrite a tag holding a different value
+	store.Write("tx-test", "3=1884", []byte("1884-12-10\n"))
+	if _, err := NewTx(dName).Add("note", "checked").Add("when", "1884").Commit(); err == nil {
+		t.Errorf("expected an error adding over a different value")
+	}
+	if src, _ := store.Read("tx-test", "3=1884"); string(src) != "1884-12-10\n" {
+		t.Errorf("expected the contents unchanged, got %q", src)
+	}
+	if s := listTags(dName); s != expected {
+		t.Errorf("expected tags unchanged, got %q", s)
+	}
+	if err := NewTx(dName).Add("note", "discarded").Rollback(); err != nil {
+		t.Errorf("Rollback() failed, %s", err)
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	store := &failingStore{MemoryStore: NewMemoryStore()}
+	RegisterStore("txtest", store)
+	defer RegisterStore("txtest", nil)
+	store.Mkdir("item1")
+	dName := "txtest://item1"
+	Who(dName, "Twain,M.")
+	What(dName, "Huck Finn")
+	before := listTags(dName)
+
+	// A failed write restores the files already written
+	store.fail = "3=1884"
+	tx := NewTx(dName).Replace("who", "Twain, Mark").Replace("what", "Huckleberry Finn").Add("when", "1884")
+	if _, err := tx.Commit(); err == nil {
+		t.Errorf("expected the commit to fail")
+	}
+	if s := listTags(dName); s != before {
+		t.Errorf("expected %q after rollback, got %q", before, s)
+	}
+
+	// A failed remove restores the removed and written files
+	store.fail = "2=Huck^20Finn"
+	tx = NewTx(dName).Replace("who", "Twain, Mark").Replace("what", "Huckleberry Finn")
+	if _, err := tx.Commit(); err == nil {
+		t.Errorf("expected the commit to fail")
+	}
+	if s := listTags(dName); s != before {
+		t.Errorf("expected %q after rollback, got %q", before, s)
+	}
+	if src, _ := store.Read("item1", "1=Twain,M."); string(src) != "Twain,M.\n" {
+		t.Errorf("expected the removed contents restored, got %q", src)
+	}
+}
@@ -0,0 +1,82 @@
+package namaste
+
+import (
+	"strings"
+)
+
+// DirectoryType is a directory type declared by a "0=" namaste tag,
+// e.g. "0=ocfl_object_1.1" is Name "ocfl_object", Major "1" and
+// Minor "1". Per Section 5 of the Namaste Spec versions are
+// "major.minor" but either part may be non-numeric (e.g. "1.0b2").
+// Major and Minor are empty if no version is declared.
+type DirectoryType struct {
+	Name  string `json:"name"`
+	Major string `json:"major,omitempty"`
+	Minor string `json:"minor,omitempty"`
+	// Raw is the filename the type was read from
+	Raw string `json:"filename,omitempty"`
+}
+
+// ParseType parses a type value such as "bagit_0.97". The version
+// follows the last underscore and must start with a digit, otherwise
+// the whole value is taken as the type name (e.g. "ocfl_object").
+func ParseType(val string) *DirectoryType {
+	t := new(DirectoryType)
+	t.Name = val
+	i := strings.LastIndex(val, "_")
+	if i < 1 || i == len(val)-1 {
+		return t
+	}
+	version := val[i+1:]
+	if version[0] < '0' || version[0] > '9' {
+		return t
+	}
+	t.Name = val[0:i]
+	if j := strings.Index(version, "."); j >= 0 {
+		t.Major, t.Minor = version[0:j], version[j+1:]
+	} else {
+		t.Major = version
+	}
+	return t
+}
+
+// Version returns the type's version, e.g. "0.97", or an empty string
+func (t *DirectoryType) Version() string {
+	if t.Minor != "" {
+		return t.Major + "." + t.Minor
+	}
+	return t.Major
+}
+
+// String returns the type as written in a tag, e.g. "bagit_0.97"
+func (t *DirectoryType) String() string {
+	if version := t.Version(); version != "" {
+		return t.Name + "_" + version
+	}
+	return t.Name
+}
+
+// GetDirTypes returns every type declared in a directory in the
+// order of their tags. Unlike GetTypes a directory declaring two
+// versions of the same type (e.g. "0=ocfl_object_1.0" and
+// "0=ocfl_object_1.1") returns both.
+func GetDirTypes(dName string) ([]*DirectoryType, error) {
+	typeTags, err := getNamaste(dName, []string{"0"})
+	if err != nil {
+		return nil, err
+	}
+	types := []*DirectoryType{}
+	for _, t := range parseTags(typeTags) {
+		val := t.Value
+		if t.Truncated {
+			// Recover the full type from the tag file's contents
+			if s, err := readNamaste(dName, t.Raw); err == nil && s != "" {
+				val = s
+			}
+		}
+		dirType := ParseType(val)
+		dirType.Raw = t.Raw
+		types = append(types, dirType)
+	}
+	return types, nil
+}
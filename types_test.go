@@ -0,0 +1,63 @@
+package namaste
+
+import (
+	"testing"
+)
+
+func TestParseType(t *testing.T) {
+	expected := map[string]DirectoryType{
+		"bagit_0.97":      DirectoryType{Name: "bagit", Major: "0", Minor: "97"},
+		"bagit_1":         DirectoryType{Name: "bagit", Major: "1"},
+		"bagit":           DirectoryType{Name: "bagit"},
+		"ocfl_object_1.1": DirectoryType{Name: "ocfl_object", Major: "1", Minor: "1"},
+		"ocfl_object":     DirectoryType{Name: "ocfl_object"},
+		"dflat_0.19b":     DirectoryType{Name: "dflat", Major: "0", Minor: "19b"},
+		"redd_1.0.3":      DirectoryType{Name: "redd", Major: "1", Minor: "0.3"},
+		"dataset_":        DirectoryType{Name: "dataset_"},
+		"_1.0":            DirectoryType{Name: "_1.0"},
+	}
+	for val, expect := range expected {
+		result := ParseType(val)
+		if *result != expect {
+			t.Errorf("ParseType(%q) expected %+v, got %+v", val, expect, result)
+		}
+		if expect.Name != val && result.String() != val {
+			t.Errorf("expected %q, got %q", val, result.String())
+		}
+	}
+}
+
+func TestGetDirTypes(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("types-test")
+	dName := mem + "types-test"
+	for _, val := range []string{"ocfl_object_1.1", "bagit_1", "ocfl_object_1.0", "dataset"} {
+		if _, err := DirType(dName, val); err != nil {
+			t.Errorf("DirType(%q) failed, %s", val, err)
+		}
+	}
+	types, err := GetDirTypes(dName)
+	if err != nil {
+		t.Errorf("GetDirTypes(%q) failed, %s", dName, err)
+		t.FailNow()
+	}
+	expected := []string{"bagit_1", "dataset", "ocfl_object_1.0", "ocfl_object_1.1"}
+	if len(types) != len(expected) {
+		t.Errorf("expected %d types, got %+v", len(expected), types)
+		t.FailNow()
+	}
+	for i, val := range expected {
+		if types[i].String() != val || types[i].Raw != "0="+val {
+			t.Errorf("expected %q, got %+v", val, types[i])
+		}
+	}
+
+	// GetTypes no longer panics on a major version only
+	m, err := GetTypes(dName)
+	if err != nil {
+		t.Errorf("GetTypes(%q) failed, %s", dName, err)
+	}
+	if m["bagit"]["major"] != "1" || len(m["bagit"]) != 2 || len(m["dataset"]) != 1 {
+		t.Errorf("unexpected types %+v", m)
+	}
+}
@@ -0,0 +1,286 @@
+package namaste
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity is how serious a validation finding is
+type Severity int
+
+const (
+	// SeverityInfo is a finding worth knowing about that isn't a problem
+	SeverityInfo Severity = iota
+	// SeverityWarning is a finding other software may trip over
+	SeverityWarning
+	// SeverityError is a finding that breaks the Namaste Spec or a
+	// type's requirements
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+// String returns the severity's name, e.g. "warning"
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name
+func (s *Severity) UnmarshalText(src []byte) error {
+	for i, name := range severityNames {
+		if strings.EqualFold(string(src), name) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not a severity", src)
+}
+
+// Finding is a problem found by Validate
+type Finding struct {
+	Severity Severity `json:"severity"`
+	// Code identifies the check, e.g. "content-mismatch"
+	Code string `json:"code"`
+	// Filename is the tag file the finding is about, if any
+	Filename string `json:"filename,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String returns the finding as a line of text
+func (f *Finding) String() string {
+	if f.Filename != "" {
+		return fmt.Sprintf("%s %s %s: %s", f.Severity, f.Code, f.Filename, f.Message)
+	}
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Code, f.Message)
+}
+
+// Report holds the findings for a directory
+type Report struct {
+	Path     string     `json:"path"`
+	Findings []*Finding `json:"findings"`
+}
+
+// add appends a finding to the report
+func (r *Report) add(severity Severity, code, filename, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, &Finding{
+		Severity: severity,
+		Code:     code,
+		Filename: filename,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Worst returns the most serious severity found, SeverityInfo if
+// there are no findings.
+func (r *Report) Worst() Severity {
+	worst := SeverityInfo
+	for _, f := range r.Findings {
+		if f.Severity > worst {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+// Validate checks the namaste tags of a directory against the Namaste
+// Spec and the profiles of the types it declares. It reports
+//
+// - conflicting versions of the same type
+// - filenames that are not correctly ^ encoded by any EncodingProfile
+// - values not in the Normalization form
+// - tags differing only by normalization, case or look alike characters
+// - contents that disagree with the filename
+// - truncated filenames without the full value in their contents
+// - unknown numeric tags (e.g. "7=...")
+// - empty values
+// - tag files without contents
+// - type profile requirements that aren't met (see CheckTypes)
+// - temporary files left by an interrupted write
+//
+// An error is returned if the directory can't be read.
+func Validate(dName string) (*Report, error) {
+	store, sName, err := OpenStore(dName)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkDir(store, sName); err != nil {
+		return nil, err
+	}
+	names, temps := []string{}, []string{}
+	err = listEach(store, sName, func(name string) error {
+		switch {
+		case strings.Index(name, "=") > 0:
+			names = append(names, name)
+		case strings.HasPrefix(name, tempPrefix) && strings.HasSuffix(name, ".tmp"):
+			temps = append(temps, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	sort.Strings(temps)
+
+	report := &Report{Path: dName, Findings: []*Finding{}}
+	for _, name := range temps {
+		report.add(SeverityInfo, "temporary-file", name, "left by an interrupted write, it is safe to remove")
+	}
+	tags := []Tag{}
+	for _, name := range names {
+		label := name[0:strings.Index(name, "=")]
+		if strings.Trim(label, "0123456789") == "" {
+			if _, ok := fieldLabels[label]; ok == false {
+				report.add(SeverityWarning, "unknown-tag", name, "%q is not a namaste field", label)
+				continue
+			}
+		}
+		tag, err := ParseTag(name)
+		if err != nil {
+			continue
+		}
+		contents, err := readNamaste(dName, name)
+		if err != nil {
+			report.add(SeverityError, "unreadable", name, "%s", err)
+			continue
+		}
+		switch {
+		case tag.Truncated && contents == "":
+			report.add(SeverityError, "truncated-without-contents", name, "filename is truncated and the full value is missing from the contents")
+		case tag.Truncated && isTruncationOf(tag.Name, contents, name) == false:
+			report.add(SeverityError, "content-mismatch", name, "contents %q disagree with the filename", contents)
+		case tag.Truncated:
+		case tag.Value == "" && contents == "":
+			report.add(SeverityWarning, "empty-value", name, "tag has an empty value")
+		case contents == "":
+			report.add(SeverityInfo, "missing-contents", name, "tag file has no contents")
+		case isTransliteration(tag.Name, contents, name):
+			// The filename is an ASCII spelling of the contents
+		case contents != tag.Value:
+			report.add(SeverityError, "content-mismatch", name, "contents %q disagree with the filename", contents)
+		}
+		switch {
+		case tag.Truncated:
+		case NormalizeValue(tag.Value) != tag.Value:
+			report.add(SeverityWarning, "not-normalized", name, "value is not in %s form", Normalization)
+		case isEncoded(tag.Name, tag.Value, name) == false:
+			report.add(SeverityWarning, "bad-encoding", name, "filename should be %q", Encode(tag.Name, tag.Value))
+		}
+		tags = append(tags, tag)
+	}
+
+	// Tags that look the same
+	for i, a := range tags {
+		for _, b := range tags[0:i] {
+			if a.Name == b.Name && a.Value != b.Value && skeleton(a.Value) == skeleton(b.Value) {
+				report.add(SeverityWarning, "confusable-tags", a.Raw, "differs from %q only by %s", b.Raw, lookAlike(a.Value, b.Value))
+			}
+		}
+	}
+
+	// Types
+	dirTypes, err := GetDirTypes(dName)
+	if err != nil {
+		return nil, err
+	}
+	for i, a := range dirTypes {
+		for _, b := range dirTypes[0:i] {
+			if a.Name == b.Name && a.Version() != b.Version() {
+				report.add(SeverityError, "conflicting-types", a.Raw, "conflicts with %q", b.Raw)
+			}
+		}
+	}
+	errs, err := CheckTypes(dName)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		filename := ""
+		if typeErr, ok := err.(*TypeError); ok {
+			for _, t := range dirTypes {
+				if t.String() == typeErr.Type {
+					filename = t.Raw
+				}
+			}
+			err = typeErr.Err
+		}
+		report.add(SeverityError, "type-requirement", filename, "%s", err)
+	}
+	return report, nil
+}
+
+// junitFailure is a failed JUnit test case
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitTestCase is a JUnit test case, one per finding
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitTestSuite is a JUnit test suite, one per report
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+// JUnitReport renders reports as JUnit XML for CI systems. Each
+// report is a test suite and each finding a test case. Warnings and
+// errors are failures, informational findings pass. A report without
+// findings holds a single passing test case.
+func JUnitReport(reports []*Report) ([]byte, error) {
+	suites := &junitTestSuites{}
+	for _, r := range reports {
+		suite := &junitTestSuite{Name: r.Path}
+		for _, f := range r.Findings {
+			testCase := &junitTestCase{
+				Name:      strings.TrimSpace(f.Code + " " + f.Filename),
+				ClassName: r.Path,
+			}
+			if f.Severity > SeverityInfo {
+				testCase.Failure = &junitFailure{
+					Type:    f.Severity.String(),
+					Message: f.Message,
+					Text:    f.String(),
+				}
+				suite.Failures++
+			} else {
+				testCase.SystemOut = f.String()
+			}
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+		if len(suite.TestCases) == 0 {
+			suite.TestCases = append(suite.TestCases, &junitTestCase{Name: "namaste", ClassName: r.Path})
+		}
+		suite.Tests = len(suite.TestCases)
+		suites.TestSuites = append(suites.TestSuites, suite)
+	}
+	src, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), src...), nil
+}
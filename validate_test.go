@@ -0,0 +1,129 @@
+package namaste
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("validate-test")
+	dName := mem + "validate-test"
+	files := map[string]string{
+		"0=ocfl_object_1.0":                "ocfl_object_1.0\n",
+		"0=ocfl_object_1.1":                "ocfl_object_1.1\n",
+		"inventory.json":                   "{}\n",
+		"inventory.json.sha512":            "\n",
+		"1=Twain, Mark":                    "Twain, Mark\n",
+		"2=Roughing^20It":                  "Roughing It, Volume 1\n",
+		"3=1872":                           "",
+		"4=":                               "",
+		"7=unknown":                        "unknown\n",
+		"2=The^20Adventures^20of^20Tom...": "",
+		"note=ok":                          "ok\n",
+		"README.md":                        "Not a tag\n",
+	}
+	for name, src := range files {
+		store.Write("validate-test", name, []byte(src))
+	}
+	report, err := Validate(dName)
+	if err != nil {
+		t.Errorf("Validate(%q) failed, %s", dName, err)
+		t.FailNow()
+	}
+	expected := []*Finding{
+		&Finding{SeverityWarning, "bad-encoding", "1=Twain, Mark", `filename should be "1=Twain,^20Mark"`},
+		&Finding{SeverityError, "content-mismatch", "2=Roughing^20It", `contents "Roughing It, Volume 1" disagree with the filename`},
+		&Finding{SeverityError, "truncated-without-contents", "2=The^20Adventures^20of^20Tom...", "filename is truncated and the full value is missing from the contents"},
+		&Finding{SeverityInfo, "missing-contents", "3=1872", "tag file has no contents"},
+		&Finding{SeverityWarning, "empty-value", "4=", "tag has an empty value"},
+		&Finding{SeverityWarning, "unknown-tag", "7=unknown", `"7" is not a namaste field`},
+		&Finding{SeverityError, "conflicting-types", "0=ocfl_object_1.1", `conflicts with "0=ocfl_object_1.0"`},
+	}
+	if len(report.Findings) != len(expected) {
+		for _, f := range report.Findings {
+			t.Logf("found %s", f)
+		}
+		t.Errorf("expected %d findings, got %d", len(expected), len(report.Findings))
+		t.FailNow()
+	}
+	for i, f := range expected {
+		if *report.Findings[i] != *f {
+			t.Errorf("expected %s, got %s", f, report.Findings[i])
+		}
+	}
+	if report.Worst() != SeverityError {
+		t.Errorf("expected worst severity error, got %s", report.Worst())
+	}
+
+	// Severities are encoded by name
+	src, _ := json.Marshal(report.Findings[0])
+	if strings.Contains(string(src), `"severity":"warning"`) == false {
+		t.Errorf("expected severity by name, got %s", src)
+	}
+	f := new(Finding)
+	if err := json.Unmarshal(src, f); err != nil || *f != *report.Findings[0] {
+		t.Errorf("expected %+v, got %+v, %v", report.Findings[0], f, err)
+	}
+
+	// Type requirements are reported
+	store.Remove("validate-test", "inventory.json")
+	report, _ = Validate(dName)
+	found := 0
+	for _, f := range report.Findings {
+		if f.Code == "type-requirement" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("expected two type requirement findings, got %d", found)
+	}
+
+	// A clean directory
+	store.Mkdir("validate-test/clean")
+	Who(dName+"/clean", "Twain, Mark")
+	report, err = Validate(dName + "/clean")
+	if err != nil || len(report.Findings) != 0 || report.Worst() != SeverityInfo {
+		t.Errorf("expected no findings, got %+v, %v", report, err)
+	}
+
+	if _, err := Validate(dName + "/missing"); err == nil {
+		t.Errorf("expected an error validating a missing directory")
+	}
+}
+
+func TestJUnitReport(t *testing.T) {
+	reports := []*Report{
+		&Report{
+			Path: "item1",
+			Findings: []*Finding{
+				&Finding{SeverityError, "content-mismatch", "1=Twain", `contents "Clemens" disagree with the filename`},
+				&Finding{SeverityInfo, "missing-contents", "2=Hamlet", "tag file has no contents"},
+			},
+		},
+		&Report{Path: "item2", Findings: []*Finding{}},
+	}
+	src, err := JUnitReport(reports)
+	if err != nil {
+		t.Errorf("JUnitReport() failed, %s", err)
+		t.FailNow()
+	}
+	suites := new(junitTestSuites)
+	if err := xml.Unmarshal(src, suites); err != nil {
+		t.Errorf("Can't parse JUnit XML, %s\n%s", err, src)
+		t.FailNow()
+	}
+	if len(suites.TestSuites) != 2 {
+		t.Errorf("expected two test suites, got %s", src)
+		t.FailNow()
+	}
+	suite := suites.TestSuites[0]
+	if suite.Name != "item1" || suite.Tests != 2 || suite.Failures != 1 || suite.TestCases[0].Failure == nil || suite.TestCases[0].Failure.Type != "error" {
+		t.Errorf("unexpected test suite %s", src)
+	}
+	if suite := suites.TestSuites[1]; suite.Tests != 1 || suite.Failures != 0 {
+		t.Errorf("expected a passing test case for a clean report, got %s", src)
+	}
+}
@@ -0,0 +1,153 @@
+package namaste
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WalkOptions controls how Walk and Find descend a tree
+type WalkOptions struct {
+	// MaxDepth limits how many levels below the root are visited,
+	// zero means no limit.
+	MaxDepth int `json:"max_depth,omitempty"`
+	// FollowSymlinks descends into symbolic links to directories,
+	// each directory is visited once even if linked more than once.
+	FollowSymlinks bool `json:"follow_symlinks,omitempty"`
+	// Ignore holds patterns (see path.Match) of directories to skip.
+	// Patterns containing a "/" are matched against the path relative
+	// to the root, otherwise against the directory's name.
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+// TaggedDir is a directory found by Find along with its namaste tags
+type TaggedDir struct {
+	Path string `json:"path"`
+	Tags []Tag  `json:"tags"`
+}
+
+// WalkFunc is called by Walk for each directory holding namaste
+// tags. Returning fs.SkipDir skips the directory's subdirectories,
+// any other error stops the walk.
+type WalkFunc func(dName string, tags []Tag) error
+
+// ignored returns true if rel (the path relative to the root)
+// matches one of the ignore patterns.
+func (opts *WalkOptions) ignored(rel string) bool {
+	for _, pattern := range opts.Ignore {
+		target := path.Base(rel)
+		if strings.Contains(pattern, "/") {
+			target = rel
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Walk descends the tree starting at root calling fn for each
+// directory that has namaste tags. Tag values are read as by Get.
+// Directories are visited in lexical order. root may name a local directory or a directory in
+// any registered store that implements DirStore (e.g. s3://BUCKET/PREFIX).
+func Walk(root string, opts *WalkOptions, fn WalkFunc) error {
+	if opts == nil {
+		opts = new(WalkOptions)
+	}
+	store, dName, err := OpenStore(root)
+	if err != nil {
+		return err
+	}
+	dirStore, ok := store.(DirStore)
+	if ok == false {
+		return fmt.Errorf("can't walk %q, storage has no subdirectories", root)
+	}
+	if err := checkDir(store, dName); err != nil {
+		return err
+	}
+	// scheme is added back to the directory names passed to fn
+	scheme := ""
+	if i := strings.Index(root, "://"); i > 0 {
+		scheme = root[0 : i+3]
+	}
+	w := &walker{
+		store:    store,
+		dirStore: dirStore,
+		scheme:   scheme,
+		opts:     opts,
+		fn:       fn,
+		visited:  map[string]bool{},
+	}
+	err = w.walk(dName, ".", 0)
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walker holds the state of a Walk
+type walker struct {
+	store    Store
+	dirStore DirStore
+	scheme   string
+	opts     *WalkOptions
+	fn       WalkFunc
+	visited  map[string]bool
+}
+
+func (w *walker) walk(dName, rel string, depth int) error {
+	if w.opts.FollowSymlinks {
+		// Avoid cycles and visiting a directory twice
+		key := dName
+		if _, ok := w.store.(*LocalStore); ok {
+			if s, err := filepath.EvalSymlinks(dName); err == nil {
+				key = s
+			}
+		}
+		if w.visited[key] {
+			return nil
+		}
+		w.visited[key] = true
+	}
+	names, dirs, err := w.dirStore.ListDir(dName, w.opts.FollowSymlinks)
+	if err != nil {
+		return err
+	}
+	if tags := parseTags(names); len(tags) > 0 {
+		if tags, err = readValues(w.scheme+dName, tags); err != nil {
+			return err
+		}
+		if err := w.fn(w.scheme+dName, tags); err != nil {
+			return err
+		}
+	}
+	if w.opts.MaxDepth > 0 && depth >= w.opts.MaxDepth {
+		return nil
+	}
+	for _, name := range dirs {
+		if w.opts.ignored(path.Join(rel, name)) {
+			continue
+		}
+		err := w.walk(path.Join(dName, name), path.Join(rel, name), depth+1)
+		if err == fs.SkipDir {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Find returns each directory with namaste tags in the tree starting
+// at root. See Walk.
+func Find(root string, opts *WalkOptions) ([]*TaggedDir, error) {
+	results := []*TaggedDir{}
+	err := Walk(root, opts, func(dName string, tags []Tag) error {
+		results = append(results, &TaggedDir{Path: dName, Tags: tags})
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,179 @@
+package namaste
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	// Setup a small collection
+	root := t.TempDir()
+	for _, dName := range []string{"item1/data", "item2/v001", "item3", "tmp/item4", "links"} {
+		if err := os.MkdirAll(path.Join(root, dName), 0775); err != nil {
+			t.Errorf("Can't create %q, %s", dName, err)
+			t.FailNow()
+		}
+	}
+	DirType(path.Join(root, "item1"), "bagit_0.97")
+	Who(path.Join(root, "item1"), "Twain, Mark")
+	DirType(path.Join(root, "item2"), "dflat_0.19")
+	DirType(path.Join(root, "item2/v001"), "dnatural_0.19")
+	DirType(path.Join(root, "tmp/item4"), "bagit_0.97")
+	os.WriteFile(path.Join(root, "item3", "README.md"), []byte("no tags here\n"), 0664)
+	if err := os.Symlink(path.Join(root, "item1"), path.Join(root, "links", "item1")); err != nil {
+		t.Errorf("Can't create symlink, %s", err)
+		t.FailNow()
+	}
+
+	found, err := Find(root, nil)
+	if err != nil {
+		t.Errorf("Find(%q) failed, %s", root, err)
+		t.FailNow()
+	}
+	expected := []string{"item1", "item2", "item2/v001", "tmp/item4"}
+	if len(found) != len(expected) {
+		t.Errorf("expected %d directories, got %d", len(expected), len(found))
+		t.FailNow()
+	}
+	for i, dir := range found {
+		if dir.Path != path.Join(root, expected[i]) {
+			t.Errorf("expected %q, got %q", path.Join(root, expected[i]), dir.Path)
+		}
+	}
+	if len(found[0].Tags) != 2 || found[0].Tags[1].Value != "Twain, Mark" {
+		t.Errorf("unexpected tags %+v", found[0].Tags)
+	}
+
+	// Depth limit and ignore patterns
+	found, err = Find(root, &WalkOptions{MaxDepth: 1, Ignore: []string{"tmp"}})
+	if err != nil {
+		t.Errorf("Find(%q) failed, %s", root, err)
+		t.FailNow()
+	}
+	if len(found) != 2 || found[0].Path != path.Join(root, "item1") || found[1].Path != path.Join(root, "item2") {
+		t.Errorf("unexpected directories %+v", found)
+	}
+	found, _ = Find(root, &WalkOptions{Ignore: []string{"item2/v*"}})
+	if len(found) != 3 {
+		t.Errorf("expected item2/v001 to be ignored, got %+v", found)
+	}
+
+	// Following symlinks visits item1 once
+	found, err = Find(root, &WalkOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Errorf("Find(%q) failed, %s", root, err)
+		t.FailNow()
+	}
+	if len(found) != len(expected) {
+		t.Errorf("expected %d directories following links, got %+v", len(expected), found)
+	}
+	os.Symlink(root, path.Join(root, "links", "loop"))
+	if _, err = Find(path.Join(root, "links"), &WalkOptions{FollowSymlinks: true}); err != nil {
+		t.Errorf("Find() with a symlink loop failed, %s", err)
+	}
+
+	// SkipDir skips subdirectories
+	visited := []string{}
+	err = Walk(root, nil, func(dName string, tags []Tag) error {
+		visited = append(visited, dName)
+		if dName == path.Join(root, "item2") {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil || len(visited) != 3 {
+		t.Errorf("expected item2/v001 to be skipped, got %+v, %v", visited, err)
+	}
+}
+
+func TestWalkStore(t *testing.T) {
+	store := NewMemoryStore()
+	RegisterStore("walktest", store)
+	defer RegisterStore("walktest", nil)
+	store.Mkdir("archive/a/item1")
+	store.Mkdir("archive/b/item2")
+	Who("walktest://archive/a/item1", "Twain, Mark")
+	What("walktest://archive/b/item2", "Roughing It")
+	found, err := Find("walktest://archive", nil)
+	if err != nil {
+		t.Errorf("Find() failed, %s", err)
+		t.FailNow()
+	}
+	if len(found) != 2 || found[0].Path != "walktest://archive/a/item1" || found[1].Tags[0].Value != "Roughing It" {
+		t.Errorf("unexpected directories %+v", found)
+	}
+}
+
+func TestFindValues(t *testing.T) {
+	store, mem := newTestStore(t)
+	store.Mkdir("archive/item1")
+	dName := mem + "archive/item1"
+	note := "Checked\nby RSD"
+	title := strings.Repeat("All work and no play makes Jack a dull boy. ", 7)
+	Note(dName, note)
+	What(dName, title)
+	check := func(fName string, tags []Tag) {
+		values := map[string]string{}
+		for _, tag := range tags {
+			values[tag.Name] = tag.Value
+		}
+		if values["note"] != note || values["2"] != title {
+			t.Errorf("%s expected the full values, got %+v", fName, tags)
+		}
+	}
+
+	found, err := Find(mem+"archive", nil)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Find() expected one directory, got %+v, %v", found, err)
+	}
+	check("Find()", found[0].Tags)
+	results, err := Scan(context.Background(), mem+"archive", nil)
+	if err != nil {
+		t.Fatalf("Scan() failed, %s", err)
+	}
+	count := 0
+	for result := range results {
+		if result.Err != nil {
+			t.Errorf("unexpected error %s", result.Err)
+		}
+		check("Scan()", result.Tags)
+		count++
+	}
+	if count != 1 {
+		t.Errorf("expected one scan result, got %d", count)
+	}
+}
+
+func TestWalkListsOnce(t *testing.T) {
+	store := &countingStore{MemoryStore: NewMemoryStore()}
+	RegisterStore("walkcount", store)
+	defer RegisterStore("walkcount", nil)
+	dirs := []string{"archive", "archive/a", "archive/a/item1", "archive/b", "archive/b/item2"}
+	for _, dName := range dirs {
+		store.Mkdir(dName)
+	}
+	Who("walkcount://archive/a/item1", "Twain, Mark")
+	What("walkcount://archive/b/item2", "Roughing It")
+
+	store.lists = 0
+	if found, err := Find("walkcount://archive", nil); err != nil || len(found) != 2 {
+		t.Errorf("Find() expected two directories, got %+v, %v", found, err)
+	}
+	if store.lists != len(dirs) {
+		t.Errorf("expected each directory listed once by Find, got %d listings", store.lists)
+	}
+	store.lists = 0
+	results, err := Scan(context.Background(), "walkcount://archive", &ScanOptions{Workers: 1})
+	if err != nil {
+		t.Fatalf("Scan() failed, %s", err)
+	}
+	for range results {
+	}
+	if store.lists != len(dirs) {
+		t.Errorf("expected each directory listed once by Scan, got %d listings", store.lists)
+	}
+}